@@ -4,7 +4,7 @@
 echo "> Creating virtualenv"
 virtualenv --quiet .env
 echo .env >>.gitignore
-source .env/bin/activate
+. .env/bin/activate
 
 # Install python packages
 echo "> Installing python packages"
@@ -17,9 +17,11 @@ func newPackageCommand() *packageCommand {
 	cmd.AddCommand(
 		newPackageAddCommand().cmd,
 		newPackageExportCommand().cmd,
+		newPackageHistoryCommand().cmd,
 		newPackageImportCommand().cmd,
 		newPackageListCommand().cmd,
 		newPackageRemoveCommand().cmd,
+		newPackageRollbackCommand().cmd,
 		newPackageShowCommand().cmd,
 	)
 
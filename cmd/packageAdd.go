@@ -41,6 +41,9 @@ func newPackageAddCommand() *packageAddCommand {
 }
 
 func addPackage(name string) error {
+	if !activeSession.interactive {
+		return fmt.Errorf("adding a package requires an interactive terminal, use 'package import' instead")
+	}
 	reader := bufio.NewReader(os.Stdin)
 
 	label, err := getLabel(reader)
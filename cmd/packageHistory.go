@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/nikoksr/proji/util"
+	"github.com/pkg/errors"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+type packageHistoryCommand struct {
+	cmd *cobra.Command
+}
+
+func newPackageHistoryCommand() *packageHistoryCommand {
+	var cmd = &cobra.Command{
+		Use:   "history LABEL",
+		Short: "List the stored versions of a package",
+		Long: `List all versions of a package in the order they were imported. Importing a config with the label of an
+existing package and a new version stores it as the current version of the package and keeps the previous ones.
+
+Use proji package rollback to make a previous version current again or proji create LABEL@VERSION to create a project
+from a specific version.`,
+		DisableFlagsInUseLine: true,
+		Args:                  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return showPackageHistory(args[0])
+		},
+	}
+	return &packageHistoryCommand{cmd: cmd}
+}
+
+func showPackageHistory(label string) error {
+	packages, err := activeSession.storageService.LoadPackageHistory(label)
+	if err != nil {
+		return errors.Wrap(err, "failed to load package history")
+	}
+
+	historyTable := util.NewInfoTable(os.Stdout)
+	historyTable.AppendHeader(table.Row{"Version", "Name", "Imported", "Templates", "Plugins", "Current"})
+	for _, pkg := range packages {
+		version := pkg.Version
+		if version == "" {
+			version = "(unversioned)"
+		}
+		current := ""
+		if !pkg.Archived {
+			current = "yes"
+		}
+		historyTable.AppendRow(table.Row{
+			version,
+			pkg.Name,
+			pkg.CreatedAt.Local().Format("2006-01-02 15:04"),
+			len(pkg.Templates),
+			len(pkg.Plugins),
+			current,
+		})
+	}
+	historyTable.Render()
+	return nil
+}
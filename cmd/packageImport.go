@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"net/url"
+	"strings"
 
 	"github.com/nikoksr/proji/messages"
 	"github.com/pkg/errors"
@@ -20,6 +21,7 @@ const (
 	flagRepoStructure      = "repo-structure"
 	flagCollection         = "collection"
 	flagPackage            = "package"
+	flagForce              = "force"
 )
 
 type packageImportCommand struct {
@@ -28,12 +30,17 @@ type packageImportCommand struct {
 
 func newPackageImportCommand() *packageImportCommand {
 	var remoteRepos, directories, configs, excludes, packages, collections []string
+	var force bool
 
 	var cmd = &cobra.Command{
 		Use:   "import FILE [FILE...]",
 		Short: "Import one or more packages",
 		PreRunE: func(cmd *cobra.Command, args []string) error {
-			if cmd.Flags().NFlag() == 0 {
+			sourceFlags := cmd.Flags().NFlag()
+			if force {
+				sourceFlags--
+			}
+			if sourceFlags == 0 {
 				if len(args) < 1 {
 					return fmt.Errorf("no config path or flag given")
 				}
@@ -60,7 +67,7 @@ func newPackageImportCommand() *packageImportCommand {
 			// Import configs
 			for importType, paths := range importTypes {
 				for _, path := range paths {
-					err := importPackage(path, importType, excludes)
+					err := importPackage(path, importType, excludes, force)
 					if err != nil {
 						messages.Warningf("failed to import package, %s", err.Error())
 					}
@@ -74,6 +81,7 @@ func newPackageImportCommand() *packageImportCommand {
 	cmd.Flags().StringSliceVarP(&remoteRepos, flagRepoStructure, "r", make([]string, 0), "create an importable config based on on the structure of a remote repository")
 	cmd.Flags().StringSliceVarP(&directories, flagDirectoryStructure, "d", make([]string, 0), "create an importable config based on the structure of a local directory")
 	cmd.Flags().StringSliceVarP(&excludes, flagExclude, "e", make([]string, 0), "folder to exclude from local directory import")
+	cmd.Flags().BoolVar(&force, flagForce, false, "replace the current version of a package even if the imported one is not newer or runs its plugins differently")
 
 	_ = cmd.MarkFlagDirname(flagDirectoryStructure)
 	_ = cmd.MarkFlagFilename(flagConfig)
@@ -82,23 +90,32 @@ func newPackageImportCommand() *packageImportCommand {
 	return &packageImportCommand{cmd: cmd}
 }
 
-func importPackage(path, importType string, excludes []string) error {
+func importPackage(path, importType string, excludes []string, force bool) error {
 	var err error
 	switch importType {
 	case flagConfig:
-		err = importPackageFromConfig(path)
+		err = importPackageFromConfig(path, force)
 	case flagDirectoryStructure:
 		err = importPackageFromDirectoryStructure(path, excludes)
 	case flagRepoStructure:
 		err = importPackageFromRepoStructure(path)
 	case flagCollection:
-		err = importPackagesFromCollection(path)
+		err = importPackagesFromCollection(path, force)
 	case flagPackage:
-		err = importPackageFromRepo(path)
+		err = importPackageFromRepo(path, force)
 	}
 	return err
 }
 
+// savePackage saves an imported package to storage. If force is set, it replaces the current version of the package
+// even if it is not newer or runs its plugins differently; see storage.SaveService.
+func savePackage(pkg *models.Package, force bool) error {
+	if force {
+		return activeSession.storageService.ReplacePackage(pkg)
+	}
+	return activeSession.storageService.SavePackage(pkg)
+}
+
 func exportPackageConfig(pkg *models.Package) error {
 	// Export package config to current working directory
 	confName, err := pkg.ExportConfig(".")
@@ -109,7 +126,7 @@ func exportPackageConfig(pkg *models.Package) error {
 	return nil
 }
 
-func importPackageFromConfig(path string) error {
+func importPackageFromConfig(path string, force bool) error {
 	// Import the package
 	pkg := models.NewPackage("", "", false)
 	err := pkg.ImportFromConfig(path)
@@ -118,7 +135,7 @@ func importPackageFromConfig(path string) error {
 	}
 
 	// Save the package
-	err = activeSession.storageService.SavePackage(pkg)
+	err = savePackage(pkg, force)
 	if err != nil {
 		return err
 	}
@@ -155,7 +172,7 @@ func importPackageFromRepoStructure(url string) error {
 	return exportPackageConfig(pkg)
 }
 
-func importPackagesFromCollection(url string) error {
+func importPackagesFromCollection(url string, force bool) error {
 	// Get parsed url and repo importer
 	parsedURL, importer, err := getURLAndRepoImporter(url)
 	if err != nil {
@@ -170,7 +187,11 @@ func importPackagesFromCollection(url string) error {
 
 	// Save the packages to storage
 	for _, pkg := range packageList {
-		err = activeSession.storageService.SavePackage(pkg)
+		if !sandboxPackage(pkg) {
+			messages.Warningf("skipped package %s, capabilities were not approved", pkg.Name)
+			continue
+		}
+		err = savePackage(pkg, force)
 		if err != nil {
 			messages.Warningf("failed to import package %s, %s", pkg.Name, err.Error())
 		} else {
@@ -180,7 +201,7 @@ func importPackagesFromCollection(url string) error {
 	return nil
 }
 
-func importPackageFromRepo(url string) error {
+func importPackageFromRepo(url string, force bool) error {
 	// Get parsed url and repo importer
 	parsedURL, importer, err := getURLAndRepoImporter(url)
 	if err != nil {
@@ -193,9 +214,12 @@ func importPackageFromRepo(url string) error {
 	if err != nil {
 		return errors.Wrap(err, "failed to import package from repository")
 	}
+	if !sandboxPackage(pkg) {
+		return fmt.Errorf("capabilities of package %s were not approved", pkg.Name)
+	}
 
 	// Save the package
-	err = activeSession.storageService.SavePackage(pkg)
+	err = savePackage(pkg, force)
 	if err != nil {
 		return errors.Wrap(err, "failed to save package")
 	}
@@ -203,6 +227,21 @@ func importPackageFromRepo(url string) error {
 	return nil
 }
 
+// sandboxPackage marks a package that was imported from a remote source as untrusted, so that its plugins run in sandbox
+// mode. If the package declares capabilities, the user is asked to approve them. Returns false if the user declined.
+func sandboxPackage(pkg *models.Package) bool {
+	pkg.Sandboxed = true
+	if len(pkg.Capabilities) == 0 {
+		return true
+	}
+	messages.Warningf(
+		"package %s requests the following capabilities for its plugins: %s",
+		pkg.Name,
+		strings.Join(pkg.Capabilities, ", "),
+	)
+	return confirm("> Do you want to grant them?")
+}
+
 // getParsedURL tries to parse an url string to an url object. The parsing will validate the given url
 // that way. If the url is valid, it returns a url object.
 func getParsedURL(url string) (*url.URL, error) {
@@ -34,13 +34,13 @@ func listPackages() error {
 	}
 
 	packagesTable := util.NewInfoTable(os.Stdout)
-	packagesTable.AppendHeader(table.Row{"Name", "Label"})
+	packagesTable.AppendHeader(table.Row{"Name", "Label", "Version"})
 
 	for _, pkg := range packages {
 		if pkg.IsDefault {
 			continue
 		}
-		packagesTable.AppendRow(table.Row{pkg.Name, pkg.Label})
+		packagesTable.AppendRow(table.Row{pkg.Name, pkg.Label, pkg.Version})
 	}
 	packagesTable.Render()
 	return nil
@@ -2,13 +2,14 @@ package cmd
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/nikoksr/proji/messages"
 
 	"github.com/pkg/errors"
 
 	"github.com/nikoksr/proji/storage/models"
-	"github.com/nikoksr/proji/util"
 
 	"github.com/spf13/cobra"
 )
@@ -48,21 +49,40 @@ func newPackageRemoveCommand() *packageRemoveCommand {
 				}
 			}
 
-			// Remove the packages
+			// Remove the packages. Packages have to exist when others extend them, so newer packages are removed first
+			sort.SliceStable(packages, func(i, j int) bool { return packages[i].ID > packages[j].ID })
 			for _, pkg := range packages {
 				// Skip default packages
 				if pkg.IsDefault {
 					continue
 				}
+				// Keep packages that others extend; they couldn't be loaded anymore
+				children, err := activeSession.storageService.LoadPackagesByParent(pkg.Label)
+				if err != nil {
+					messages.Warningf("failed to remove package %s, %s", pkg.Label, err.Error())
+					continue
+				}
+				if len(children) > 0 {
+					labels := make([]string, 0, len(children))
+					for _, child := range children {
+						labels = append(labels, child.Label)
+					}
+					messages.Warningf(
+						"failed to remove package %s, it is extended by %s",
+						pkg.Label,
+						strings.Join(labels, ", "),
+					)
+					continue
+				}
 				// Ask for confirmation if force flag was not passed
 				if !forceRemovePackages {
-					if !util.WantTo(
+					if !confirm(
 						fmt.Sprintf("Do you really want to remove package '%s (%s)'?", pkg.Name, pkg.Label),
 					) {
 						continue
 					}
 				}
-				err := activeSession.storageService.RemovePackage(pkg.Label)
+				err = activeSession.storageService.RemovePackage(pkg.Label)
 				if err != nil {
 					messages.Warningf("failed to remove package %s, %s", pkg.Label, err.Error())
 				} else {
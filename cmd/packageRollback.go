@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"github.com/nikoksr/proji/messages"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type packageRollbackCommand struct {
+	cmd *cobra.Command
+}
+
+func newPackageRollbackCommand() *packageRollbackCommand {
+	var cmd = &cobra.Command{
+		Use:   "rollback LABEL VERSION",
+		Short: "Make a previous version of a package current again",
+		Long: `Make a previous version of a package its current version again. New projects are created from it and
+proji update applies it to existing projects. Newer versions are kept and can be made current again the same way; see
+proji package history.`,
+		DisableFlagsInUseLine: true,
+		Args:                  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			label, version := args[0], args[1]
+			err := activeSession.storageService.RollbackPackage(label, version)
+			if err != nil {
+				return errors.Wrap(err, "failed to roll back package")
+			}
+			messages.Successf("version %s is the current version of package %s", version, label)
+			return nil
+		},
+	}
+	return &packageRollbackCommand{cmd: cmd}
+}
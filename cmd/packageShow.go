@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 
 	"github.com/nikoksr/proji/messages"
 
@@ -53,9 +54,14 @@ func showPackage(preloadedPackage *models.Package, label string) error {
 		}
 	}
 	output := os.Stdout
-	showBasicInfo(preloadedPackage.Name, preloadedPackage.Label, preloadedPackage.Description)
-	showTemplates(output, preloadedPackage.Templates)
-	showPlugins(output, preloadedPackage.Plugins)
+	showBasicInfo(preloadedPackage.Name, preloadedPackage.Label, preloadedPackage.Version, preloadedPackage.Description)
+	showSandbox(preloadedPackage.Sandboxed, preloadedPackage.Capabilities)
+	showProjectsRoot(preloadedPackage.ProjectsRoot)
+	showExtends(preloadedPackage.Extends)
+	showOrigin := len(preloadedPackage.Extends) > 0
+	showTemplates(output, preloadedPackage.Templates, showOrigin)
+	showPlugins(output, append(preloadedPackage.PrePlugins(), preloadedPackage.PostPlugins()...), showOrigin)
+	showVariables(output, preloadedPackage.Variables, showOrigin)
 	return nil
 }
 
@@ -73,43 +79,130 @@ func showPackages(labels ...string) error {
 	return nil
 }
 
-func showBasicInfo(name, label, description string) {
+func showBasicInfo(name, label, version, description string) {
 	fmt.Printf("\nName:  %s\n", name)
 	fmt.Printf("Label: %s\n", label)
+	if version != "" {
+		fmt.Printf("Version: %s\n", version)
+	}
 	fmt.Printf("Description: %s\n\n", text.WrapSoft(description, activeSession.maxTableColumnWidth))
 }
 
-func showTemplates(out io.Writer, templates []*models.Template) {
+func showSandbox(sandboxed bool, capabilities []string) {
+	if !sandboxed {
+		return
+	}
+	granted := "none"
+	if len(capabilities) > 0 {
+		granted = strings.Join(capabilities, ", ")
+	}
+	fmt.Printf("Sandboxed: yes (capabilities: %s)\n\n", granted)
+}
+
+func showProjectsRoot(projectsRoot string) {
+	if projectsRoot == "" {
+		return
+	}
+	fmt.Printf("Projects root: %s\n\n", projectsRoot)
+}
+
+func showExtends(extends []string) {
+	if len(extends) == 0 {
+		return
+	}
+	fmt.Printf("Extends: %s\n\n", strings.Join(extends, ", "))
+}
+
+// withOrigin appends the package that a definition was inherited from to a table row if showOrigin is set. Own
+// definitions have no origin.
+func withOrigin(row table.Row, inherited string, showOrigin bool) table.Row {
+	if !showOrigin {
+		return row
+	}
+	return append(row, inherited)
+}
+
+func showTemplates(out io.Writer, templates []*models.Template, showOrigin bool) {
 	templatesTable := util.NewInfoTable(out)
 	templatesTable.SetTitle("TEMPLATES")
-	templatesTable.AppendHeader(table.Row{"Destination", "Template Path", "Is File", "Description"})
+	templatesTable.AppendHeader(withOrigin(
+		table.Row{"Destination", "Template Path", "Is File", "Mode", "When", "Description"},
+		"Inherited From",
+		showOrigin,
+	))
 
 	for _, template := range templates {
-		templatesTable.AppendRow(
+		templatePath := template.Path
+		switch {
+		case template.Content != "":
+			templatePath = "(inline)"
+		case template.Symlink != "":
+			templatePath = "-> " + template.Symlink
+		}
+		templatesTable.AppendRow(withOrigin(
 			table.Row{
 				template.Destination,
-				template.Path,
+				templatePath,
 				template.IsFile,
+				template.Mode,
+				template.When,
 				template.Description,
 			},
-		)
+			template.Inherited,
+			showOrigin,
+		))
 	}
 	templatesTable.Render()
 }
 
-func showPlugins(out io.Writer, plugins []*models.Plugin) {
+func showPlugins(out io.Writer, plugins []*models.Plugin, showOrigin bool) {
 	pluginsTable := util.NewInfoTable(out)
 	pluginsTable.SetTitle("PLUGINS")
-	pluginsTable.AppendHeader(table.Row{"Path", "Execution Number", "Description"})
+	pluginsTable.AppendHeader(withOrigin(
+		table.Row{"Path", "Type", "Execution Number", "Args", "When", "Description"},
+		"Inherited From",
+		showOrigin,
+	))
 
 	for _, plugin := range plugins {
-		pluginsTable.AppendRow(
+		pluginsTable.AppendRow(withOrigin(
 			table.Row{
 				plugin.Path,
+				plugin.ResolvedType(),
 				plugin.ExecNumber,
+				strings.Join(plugin.Args, " "),
+				plugin.When,
 				text.WrapSoft(plugin.Description, activeSession.maxTableColumnWidth),
 			},
-		)
+			plugin.Inherited,
+			showOrigin,
+		))
 	}
 	pluginsTable.Render()
 }
+
+func showVariables(out io.Writer, variables []*models.Variable, showOrigin bool) {
+	variablesTable := util.NewInfoTable(out)
+	variablesTable.SetTitle("VARIABLES")
+	variablesTable.AppendHeader(withOrigin(
+		table.Row{"Name", "Type", "Default", "Choices", "Regex", "Prompt"},
+		"Inherited From",
+		showOrigin,
+	))
+
+	for _, variable := range variables {
+		variablesTable.AppendRow(withOrigin(
+			table.Row{
+				variable.Name,
+				variable.Type,
+				variable.Default,
+				strings.Join(variable.Choices, ", "),
+				variable.Regex,
+				text.WrapSoft(variable.Prompt, activeSession.maxTableColumnWidth),
+			},
+			variable.Inherited,
+			showOrigin,
+		))
+	}
+	variablesTable.Render()
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/nikoksr/proji/storage/models"
+	"github.com/spf13/cobra"
+)
+
+type pluginCommand struct {
+	cmd *cobra.Command
+}
+
+func newPluginCommand() *pluginCommand {
+	var cmd = &cobra.Command{
+		Use:   "plugin",
+		Short: "Manage plugins",
+	}
+
+	cmd.AddCommand(
+		newPluginAddCommand().cmd,
+		newPluginListCommand().cmd,
+		newPluginRemoveCommand().cmd,
+		newPluginRunCommand().cmd,
+		newPluginShowCommand().cmd,
+	)
+
+	return &pluginCommand{cmd: cmd}
+}
+
+// pluginsFolder returns the path of proji's plugins folder.
+func pluginsFolder() string {
+	return filepath.Join(activeSession.config.BasePath, "plugins")
+}
+
+// pluginPath returns the absolute path of the plugin at the given path relative to the plugins folder. Paths that lie
+// outside of the plugins folder are rejected.
+func pluginPath(path string) (string, error) {
+	return (&models.Plugin{Path: path}).AbsolutePath(pluginsFolder())
+}
+
+// listPluginFiles returns the paths of all files in the plugins folder relative to the plugins folder.
+func listPluginFiles() ([]string, error) {
+	root := pluginsFolder()
+	files := make([]string, 0)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, rel)
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return files, nil
+	}
+	sort.Strings(files)
+	return files, err
+}
+
+// findPlugin returns the plugin of the package with the given path or nil if the package doesn't use the plugin.
+func findPlugin(pkg *models.Package, path string) *models.Plugin {
+	for _, plugin := range pkg.Plugins {
+		if plugin.Path == path {
+			return plugin
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/nikoksr/proji/messages"
+	"github.com/nikoksr/proji/util"
+	"github.com/spf13/cobra"
+)
+
+type pluginAddCommand struct {
+	cmd *cobra.Command
+}
+
+func newPluginAddCommand() *pluginAddCommand {
+	var forceOverwrite bool
+	var name string
+
+	var cmd = &cobra.Command{
+		Use:   "add FILE [FILE...]",
+		Short: "Copy one or more files to the plugins folder",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if name != "" && len(args) > 1 {
+				return fmt.Errorf("the flag 'name' can only be used when adding a single plugin")
+			}
+			for _, src := range args {
+				dst := filepath.Base(src)
+				if name != "" {
+					dst = name
+				}
+				err := addPlugin(src, dst, forceOverwrite)
+				if err != nil {
+					messages.Warningf("failed to add plugin %s, %s", src, err.Error())
+				} else {
+					messages.Successf("successfully added plugin %s", dst)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&forceOverwrite, "force", "f", false, "overwrite existing plugins without asking")
+	cmd.Flags().StringVarP(&name, "name", "n", "", "path of the plugin inside of the plugins folder")
+	return &pluginAddCommand{cmd: cmd}
+}
+
+// addPlugin copies the file src to the path dst inside of the plugins folder. The file mode is preserved so that
+// executable plugins stay executable.
+func addPlugin(src, dst string, forceOverwrite bool) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory", src)
+	}
+
+	dst, err = pluginPath(dst)
+	if err != nil {
+		return err
+	}
+	if util.DoesPathExist(dst) && !forceOverwrite {
+		if !confirm(fmt.Sprintf("> Plugin %s already exists. Do you want to overwrite it?", dst)) {
+			return fmt.Errorf("plugin %s already exists", dst)
+		}
+	}
+
+	content, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(dst), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(dst, content, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	// WriteFile does not change the mode of existing files
+	return os.Chmod(dst, info.Mode().Perm())
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/nikoksr/proji/plugin"
+	"github.com/nikoksr/proji/util"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type pluginListCommand struct {
+	cmd *cobra.Command
+}
+
+func newPluginListCommand() *pluginListCommand {
+	var cmd = &cobra.Command{
+		Use:                   "ls",
+		Short:                 "List plugins and the packages that use them",
+		DisableFlagsInUseLine: true,
+		Args:                  cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listPlugins()
+		},
+	}
+	return &pluginListCommand{cmd: cmd}
+}
+
+// listPlugins lists the files of the plugins folder together with the packages that use them. Plugins that are used by
+// packages but don't exist in the plugins folder are listed as missing.
+func listPlugins() error {
+	files, err := listPluginFiles()
+	if err != nil {
+		return errors.Wrap(err, "failed to list plugins folder")
+	}
+	packages, err := activeSession.storageService.LoadPackages()
+	if err != nil {
+		return errors.Wrap(err, "failed to load all packages")
+	}
+
+	// Collect the labels of the packages that use a plugin
+	usages := make(map[string][]string)
+	for _, pkg := range packages {
+		for _, p := range pkg.Plugins {
+			usages[p.Path] = append(usages[p.Path], pkg.Label)
+		}
+	}
+
+	pluginsTable := util.NewInfoTable(os.Stdout)
+	pluginsTable.AppendHeader(table.Row{"Path", "Type", "Used By", "Status"})
+
+	existing := make(map[string]bool, len(files))
+	for _, file := range files {
+		existing[file] = true
+		status := "ok"
+		if len(usages[file]) == 0 {
+			status = "unused"
+		}
+		pluginsTable.AppendRow(table.Row{file, plugin.TypeOf(file), strings.Join(usages[file], ", "), status})
+	}
+
+	missing := make([]string, 0)
+	for path := range usages {
+		// Plugins with an absolute path live outside of the plugins folder
+		if !existing[path] && !(filepath.IsAbs(path) && util.DoesPathExist(path)) {
+			missing = append(missing, path)
+		}
+	}
+	sort.Strings(missing)
+	for _, path := range missing {
+		pluginsTable.AppendRow(table.Row{path, plugin.TypeOf(path), strings.Join(usages[path], ", "), "missing"})
+	}
+	pluginsTable.Render()
+	return nil
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nikoksr/proji/messages"
+	"github.com/spf13/cobra"
+)
+
+type pluginRemoveCommand struct {
+	cmd *cobra.Command
+}
+
+func newPluginRemoveCommand() *pluginRemoveCommand {
+	var forceRemovePlugins bool
+
+	var cmd = &cobra.Command{
+		Use:   "rm PATH [PATH...]",
+		Short: "Remove one or more plugins from the plugins folder",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, path := range args {
+				removed, err := removePlugin(path, forceRemovePlugins)
+				if err != nil {
+					messages.Warningf("failed to remove plugin %s, %s", path, err.Error())
+				} else if removed {
+					messages.Successf("successfully removed plugin %s", path)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&forceRemovePlugins, "force", "f", false, "Don't ask for confirmation and remove plugins that are still in use")
+	return &pluginRemoveCommand{cmd: cmd}
+}
+
+// removePlugin removes a plugin file from the plugins folder. Plugins that are still used by packages are only removed
+// if force is true. Returns whether the plugin was removed.
+func removePlugin(path string, force bool) (bool, error) {
+	location, err := pluginPath(path)
+	if err != nil {
+		return false, err
+	}
+
+	packages, err := activeSession.storageService.LoadPackagesByPlugin(path)
+	if err != nil {
+		return false, err
+	}
+	if len(packages) > 0 && !force {
+		labels := make([]string, 0, len(packages))
+		for _, pkg := range packages {
+			labels = append(labels, pkg.Label)
+		}
+		return false, fmt.Errorf("plugin is used by the packages %s", strings.Join(labels, ", "))
+	}
+
+	if !force && !confirm(fmt.Sprintf("Do you really want to remove plugin '%s'?", path)) {
+		return false, nil
+	}
+	return true, os.Remove(location)
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/nikoksr/proji/messages"
+	"github.com/nikoksr/proji/render"
+	"github.com/nikoksr/proji/storage/models"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type pluginRunCommand struct {
+	cmd *cobra.Command
+}
+
+func newPluginRunCommand() *pluginRunCommand {
+	var label, pluginType, timeout, valuesFile string
+	var pluginArgs, setValues []string
+
+	var cmd = &cobra.Command{
+		Use:   "run PATH [DIRECTORY]",
+		Short: "Run a plugin against a directory",
+		Long: `Run a plugin against a directory as if it was run during the creation of a project in that directory.
+
+If a package is given, the plugin is run with the package's settings for the plugin and the package variables are
+resolved like they are on project creation. Flags take precedence over the package settings.`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := args[0]
+			directory := "."
+			if len(args) > 1 {
+				directory = args[1]
+			}
+			directory, err := filepath.Abs(directory)
+			if err != nil {
+				return errors.Wrap(err, "failed to get absolute path of directory")
+			}
+			info, err := os.Stat(directory)
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", directory)
+			}
+
+			// Use the package's settings for the plugin if a package was given
+			pkg := models.NewPackage("", "", false)
+			if label != "" {
+				pkg, err = activeSession.storageService.LoadPackage(label)
+				if err != nil {
+					return errors.Wrap(err, "failed to load package")
+				}
+			}
+			p := &models.Plugin{Path: path}
+			if packagePlugin := findPlugin(pkg, path); packagePlugin != nil {
+				pluginCopy := *packagePlugin
+				p = &pluginCopy
+			}
+			if cmd.Flags().Changed("arg") {
+				p.Args = pluginArgs
+			}
+			if pluginType != "" {
+				p.Type = pluginType
+			}
+			if timeout != "" {
+				p.Timeout = timeout
+			}
+			err = p.Validate()
+			if err != nil {
+				return err
+			}
+
+			// Resolve the variable values
+			presets, err := loadPresetValues(valuesFile, setValues)
+			if err != nil {
+				return errors.Wrap(err, "failed to load variable values")
+			}
+			values := make(render.Vars, len(presets))
+			if label != "" {
+				warnUnknownPresets(pkg.Variables, presets)
+				values, err = resolveVariables(pkg.Variables, presets)
+				if err != nil {
+					return errors.Wrap(err, "failed to resolve variables")
+				}
+			} else {
+				for name, value := range presets {
+					values[name] = value
+				}
+			}
+
+			return runPlugin(p, directory, pkg, values)
+		},
+	}
+
+	cmd.Flags().StringVarP(&label, "package", "p", "", "run the plugin with the settings and variables of a package")
+	cmd.Flags().StringArrayVar(&pluginArgs, "arg", make([]string, 0), "pass an argument to the plugin; can be repeated")
+	cmd.Flags().StringVar(&pluginType, "type", "", "type of the plugin (lua, shell, exec); inferred from the path by default")
+	cmd.Flags().StringVar(&timeout, "timeout", "", "abort the plugin after the given duration, e.g. 30s")
+	cmd.Flags().StringArrayVar(&setValues, "set", make([]string, 0), "set a variable value (key=value); can be repeated")
+	cmd.Flags().StringVar(&valuesFile, "values", "", "load variable values from a toml, json or yaml file")
+	_ = cmd.MarkFlagFilename("values", "toml", "json", "yaml", "yml")
+
+	return &pluginRunCommand{cmd: cmd}
+}
+
+// runPlugin runs the plugin in the given directory. The directory is treated as the folder of a project that is based
+// on the given package.
+func runPlugin(p *models.Plugin, directory string, pkg *models.Package, values render.Vars) error {
+	ctx, stop := interruptContext()
+	defer stop()
+
+	project := models.NewProject(filepath.Base(directory), directory, pkg)
+	project.Variables = values
+	project.PluginTimeout = activeSession.config.Plugins.Timeout
+
+	messages.Infof("running plugin %s in %s", p.Path, directory)
+	err := p.Run(ctx, project.PluginEnv(activeSession.config.BasePath))
+	if err != nil {
+		return errors.Wrap(err, "plugin failed")
+	}
+	messages.Successf("successfully ran plugin %s", p.Path)
+	return nil
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/nikoksr/proji/messages"
+	"github.com/nikoksr/proji/plugin"
+	"github.com/nikoksr/proji/util"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type pluginShowCommand struct {
+	cmd *cobra.Command
+}
+
+func newPluginShowCommand() *pluginShowCommand {
+	var cmd = &cobra.Command{
+		Use:                   "show PATH [PATH...]",
+		Short:                 "Show details about one or more plugins",
+		DisableFlagsInUseLine: true,
+		Args:                  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, path := range args {
+				err := showPlugin(path)
+				if err != nil {
+					messages.Warningf("failed to show plugin %s, %s", path, err.Error())
+				}
+			}
+			return nil
+		},
+	}
+	return &pluginShowCommand{cmd: cmd}
+}
+
+// showPlugin shows where a plugin is located and how the packages that use it have configured it.
+func showPlugin(path string) error {
+	packages, err := activeSession.storageService.LoadPackagesByPlugin(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to load packages")
+	}
+
+	location, err := pluginPath(path)
+	if err != nil {
+		return err
+	}
+	exists := "yes"
+	if !util.DoesPathExist(location) {
+		exists = "no"
+	}
+	fmt.Printf("\nPath:     %s\n", path)
+	fmt.Printf("Location: %s\n", location)
+	fmt.Printf("Type:     %s\n", plugin.TypeOf(path))
+	fmt.Printf("Exists:   %s\n\n", exists)
+
+	packagesTable := util.NewInfoTable(os.Stdout)
+	packagesTable.SetTitle("PACKAGES")
+	packagesTable.AppendHeader(table.Row{
+		"Label", "Name", "Type", "Execution Number", "Args", "Timeout", "On Failure", "When",
+	})
+	for _, pkg := range packages {
+		p := findPlugin(pkg, path)
+		if p == nil {
+			continue
+		}
+		packagesTable.AppendRow(table.Row{
+			pkg.Label,
+			pkg.Name,
+			p.ResolvedType(),
+			p.ExecNumber,
+			strings.Join(p.Args, " "),
+			p.Timeout,
+			p.FailurePolicy(),
+			p.When,
+		})
+	}
+	packagesTable.Render()
+	return nil
+}
@@ -20,7 +20,7 @@ type projectAddCommand struct {
 
 func newProjectAddCommand() *projectAddCommand {
 	var cmd = &cobra.Command{
-		Use:                   "add LABEL PATH",
+		Use:                   "add LABEL[,LABEL...] PATH",
 		Short:                 "Add an existing project",
 		DisableFlagsInUseLine: true,
 		Args:                  cobra.ExactArgs(2),
@@ -48,12 +48,17 @@ func newProjectAddCommand() *projectAddCommand {
 
 func addProject(label, path string) error {
 	name := filepath.Base(path)
-	pkg, err := activeSession.storageService.LoadPackage(label)
+	pkg, err := loadPackages(label)
 	if err != nil {
-		return errors.Wrap(err, "failed to load package")
+		return err
 	}
 
+	// Keep track of what proji generated if the project was created by proji before
 	project := models.NewProject(name, path, pkg)
+	project.Manifest, err = models.ReadManifest(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to read manifest")
+	}
 	err = activeSession.storageService.SaveProject(project)
 	if err != nil {
 		return errors.Wrap(err, "failed to save package")
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nikoksr/proji/messages"
+	"github.com/nikoksr/proji/storage/models"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type projectCheckCommand struct {
+	cmd *cobra.Command
+}
+
+// projectDrift is the result of checking a single project for drift.
+type projectDrift struct {
+	Name    string          `json:"name"`
+	Path    string          `json:"path"`
+	Package string          `json:"package"`
+	Missing []string        `json:"missing_packages,omitempty"`
+	Drift   []*models.Drift `json:"drift"`
+	Error   string          `json:"error,omitempty"`
+}
+
+func newProjectCheckCommand() *projectCheckCommand {
+	var checkAll, jsonOutput bool
+
+	var cmd = &cobra.Command{
+		Use:   "check [PATH...]",
+		Short: "Check projects for drift from their package",
+		Long: `Compare projects with what their package would generate now. Defaults to the project in the current working
+directory.
+
+Each file, folder and symlink that differs is reported as one of:
+
+  missing    the package defines it but it doesn't exist in the project
+  modified   it was changed in the project since it was generated
+  outdated   the package generates it differently by now
+  obsolete   it was generated but the package doesn't define it anymore
+
+Projects are checked against the manifest that proji keeps in their .proji folder. Projects that were created before
+proji kept manifests are compared with the package only.
+
+proji exits with a non-zero status if any project drifted or couldn't be checked, which makes the command suitable for
+CI pipelines; e.g. proji check --all --json.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if checkAll && len(args) > 0 {
+				return fmt.Errorf("--all can't be combined with paths")
+			}
+			if !checkAll && len(args) < 1 {
+				args = []string{"."}
+			}
+			paths := make([]string, 0, len(args))
+			for _, path := range args {
+				path, err := filepath.Abs(path)
+				if err != nil {
+					return err
+				}
+				paths = append(paths, path)
+			}
+
+			projects, err := activeSession.storageService.LoadProjects(paths...)
+			if err != nil {
+				return errors.Wrap(err, "failed to load projects")
+			}
+			results := make([]*projectDrift, 0, len(projects))
+			drifted := 0
+			for _, project := range projects {
+				result := checkProject(project)
+				if result.Error != "" || len(result.Drift) > 0 {
+					drifted++
+				}
+				results = append(results, result)
+			}
+
+			if jsonOutput {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				err = encoder.Encode(results)
+				if err != nil {
+					return err
+				}
+			} else {
+				showDrift(results)
+			}
+			if drifted > 0 {
+				return fmt.Errorf("%d of %d projects drifted from their package", drifted, len(results))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&checkAll, "all", "a", false, "check all projects")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "print the results as json")
+
+	return &projectCheckCommand{cmd: cmd}
+}
+
+// checkProject checks a single project for drift. Failures are recorded in the result.
+func checkProject(project *models.Project) *projectDrift {
+	result := &projectDrift{Name: project.Name, Path: project.Path, Drift: make([]*models.Drift, 0)}
+	if project.Package == nil || project.Package.ID == 0 {
+		result.Error = "package of project not found"
+		return result
+	}
+	result.Package = strings.Join(project.Package.Labels(), ",")
+	result.Missing = project.Missing
+
+	err := resolveProjectVariables(project, nil)
+	if err == nil {
+		result.Drift, err = project.CheckDrift(activeSession.config.BasePath)
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// showDrift prints the results of a check in a human readable form.
+func showDrift(results []*projectDrift) {
+	for _, result := range results {
+		for _, label := range result.Missing {
+			messages.Warningf("package %s of project %s was removed", label, result.Name)
+		}
+		switch {
+		case result.Error != "":
+			messages.Warningf("failed to check project %s at %s, %s", result.Name, result.Path, result.Error)
+		case len(result.Drift) == 0:
+			messages.Successf("project %s matches package %s", result.Name, result.Package)
+		default:
+			messages.Warningf("project %s at %s drifted from package %s", result.Name, result.Path, result.Package)
+			for _, drift := range result.Drift {
+				fmt.Printf("  %-9s %s\n", drift.Kind, drift.Path)
+			}
+		}
+	}
+}
@@ -1,14 +1,21 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 
 	"github.com/nikoksr/proji/messages"
+	"github.com/nikoksr/proji/render"
 
 	"github.com/nikoksr/proji/storage"
 	"github.com/pkg/errors"
 
+	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/nikoksr/proji/storage/models"
 	"github.com/nikoksr/proji/util"
 	"github.com/spf13/cobra"
@@ -19,92 +26,422 @@ type projectCreateCommand struct {
 }
 
 func newProjectCreateCommand() *projectCreateCommand {
+	var shareValues bool
+	var setValues []string
+	var valuesFile string
+	var plan bool
+	var atomic bool
+	var dryRun bool
+	var into, conflictStrategy, output string
+	var jobs int
+
 	var cmd = &cobra.Command{
-		Use:                   "create LABEL NAME [NAME...]",
-		Short:                 "Create one or more projects",
+		Use:   "create LABEL[@VERSION][,LABEL...] NAME [NAME...]",
+		Short: "Create one or more projects",
+		Long: `Create one or more projects. Multiple projects are created at the same time; use --jobs to limit how many.
+Variables are resolved for all projects before the first one is created.
+
+Pass several comma separated labels to compose a project of multiple packages, e.g. proji create go,docker my-service.
+The packages are layered onto each other from left to right: a template with the same destination, a plugin with the
+same path or a variable with the same name as one of an earlier package replaces it. Replaced templates are reported as
+conflicts. Plugins of different packages that share an execution number run in the order of the packages. The first
+package is the primary package of the project; its name and label are the ones that templates refer to.
+
+Projects are created from the current version of a package unless a label pins one, e.g. proji create go@1.2.0 my-app.
+See proji package history for the stored versions of a package.
+
+Projects are created in the current working directory by default. Use --output or set projects_root in the package
+config to create them somewhere else. Both are path templates; e.g. ~/src/{{ .Package.Label }}. The project folder is
+created inside of the given path unless the template references the project name, e.g. ~/src/{{ .Name }}-go.
+
+Use --into to apply a package to an existing folder instead, e.g. to add the structure of a package to an existing
+repository. The conflict strategy decides what happens to existing files that a template would replace:
+
+  skip       keep the existing file
+  overwrite  replace the existing file
+  backup     rename the existing file to <file>.bak and create the new one
+  prompt     ask for every file and offer to show a diff; files are skipped if proji runs non-interactively`,
 		DisableFlagsInUseLine: true,
-		Args:                  cobra.MinimumNArgs(2),
+		Args: func(cmd *cobra.Command, args []string) error {
+			if plan {
+				return cobra.MinimumNArgs(1)(cmd, args)
+			}
+			if into != "" {
+				return cobra.ExactArgs(1)(cmd, args)
+			}
+			return cobra.MinimumNArgs(2)(cmd, args)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			label := args[0]
 			projectNames := args[1:]
 
+			if plan {
+				return showPlan(label)
+			}
+
 			// Get current working directory
 			workingDirectory, err := os.Getwd()
 			if err != nil {
 				return errors.Wrap(err, "failed to get working directory")
 			}
 
+			if jobs < 1 {
+				return fmt.Errorf("jobs has to be at least 1, got %d", jobs)
+			}
+
+			if into != "" && output != "" {
+				return fmt.Errorf("--into and --output cannot be used together")
+			}
+
+			// Files that were overwritten in an existing folder can't be restored, so there is nothing to roll back to
+			if into != "" && atomic {
+				return fmt.Errorf("--into and --atomic cannot be used together")
+			}
+
+			options := createOptions{atomic: atomic}
+			if into != "" {
+				// The project is named after the folder it is created in
+				into, err = filepath.Abs(into)
+				if err != nil {
+					return errors.Wrap(err, "failed to get absolute path of folder")
+				}
+				workingDirectory = filepath.Dir(into)
+				projectNames = []string{filepath.Base(into)}
+				options.allowExisting = true
+				options.conflict, err = conflictFunc(conflictStrategy)
+				if err != nil {
+					return err
+				}
+			}
+
 			// Load package once for all projects
-			pkg, err := activeSession.storageService.LoadPackage(label)
+			pkg, err := loadPackages(label)
 			if err != nil {
-				return errors.Wrap(err, "failed to load package")
+				return err
 			}
 
+			// Load variable values that were passed by file or flag
+			presets, err := loadPresetValues(valuesFile, setValues)
+			if err != nil {
+				return errors.Wrap(err, "failed to load variable values")
+			}
+			warnUnknownPresets(pkg.Variables, presets)
+
+			// Resolve the variables of all projects up front; the projects are created concurrently afterwards
+			projects := make([]*models.Project, 0, len(projectNames))
+			var values render.Vars
 			for _, projectName := range projectNames {
-				messages.Infof("creating project %s", projectName)
+				// Resolve the package variables once per project or once for all projects
+				if values == nil || !shareValues {
+					if activeSession.interactive && !shareValues && len(pkg.Variables) > len(presets) {
+						messages.Infof("variables for project %s", projectName)
+					}
+					values, err = resolveVariables(pkg.Variables, presets)
+					if err != nil {
+						return errors.Wrap(err, "failed to resolve variables")
+					}
+				}
 
-				// Try to create the project
 				projectPath := filepath.Join(workingDirectory, projectName)
-				err := createProject(projectName, projectPath, pkg)
+				if into == "" {
+					projectPath, err = resolveProjectPath(output, projectName, pkg, values, workingDirectory)
+					if err != nil {
+						return errors.Wrapf(err, "failed to resolve path of project %s", projectName)
+					}
+				}
+				if dryRun {
+					err = showDryRun(projectName, projectPath, pkg, values, options.allowExisting)
+					if err != nil {
+						return errors.Wrapf(err, "failed to plan project %s", projectName)
+					}
+					continue
+				}
+				projects = append(projects, newProject(projectName, projectPath, pkg, values, options))
+			}
+			if len(projects) == 0 {
+				return nil
+			}
+
+			// Abort running plugins and skip remaining projects on Ctrl-C
+			ctx, stop := interruptContext()
+			defer stop()
+			creationErrs := createProjects(ctx, projects, jobs)
+
+			// Save the projects and report the results in the order the projects were given
+			failed := make([]string, 0)
+			for i, project := range projects {
+				err := finishProject(project, creationErrs[i], options)
 				if err == nil {
-					messages.Successf("successfully created project %s", projectName)
+					messages.Successf("successfully created project %s", project.Name)
 					continue
 				}
 
 				// Print error message
-				messages.Warningf("failed to create project, %s", projectName, err.Error())
+				messages.Warningf("failed to create project %s, %s", project.Name, err.Error())
 
 				// Check if error is because of a project is already associated with this path. Continue loop if so.
 				_, projectExists := err.(*storage.ProjectExistsError)
 				if !projectExists {
+					failed = append(failed, project.Name)
 					continue
 				}
 
 				// Continue if use doesn't want to replace the project.
-				if !util.WantTo("> Do you want to replace it?") {
+				if !confirm("> Do you want to replace it?") {
+					failed = append(failed, project.Name)
 					continue
 				}
 
 				// Try to replace the project
-				err = replaceProject(projectName, projectPath, pkg)
+				err = replaceProject(project)
 				if err != nil {
-					messages.Warningf("failed to replace project %s, %s", projectName, err.Error())
+					failed = append(failed, project.Name)
+					messages.Warningf("failed to replace project %s, %s", project.Name, err.Error())
 				} else {
-					messages.Successf("successfully replaced project %s", projectName)
+					messages.Successf("successfully replaced project %s", project.Name)
 				}
 			}
+			showCreationSummary(len(projects), failed)
+			if len(failed) > 0 {
+				return fmt.Errorf("%d of %d projects failed", len(failed), len(projects))
+			}
 			return nil
 		},
 	}
 
+	cmd.Flags().BoolVar(&shareValues, "share-values", false, "ask for variables only once and use the values for all projects")
+	cmd.Flags().StringArrayVar(&setValues, "set", make([]string, 0), "set a variable value (key=value); can be repeated")
+	cmd.Flags().StringVar(&valuesFile, "values", "", "load variable values from a toml, json or yaml file")
+	cmd.Flags().BoolVar(&atomic, "atomic", false, "remove the project folder again if the creation fails")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "show what would be created and which plugins would run without creating anything")
+	cmd.Flags().BoolVar(&plan, "plan", false, "show the order in which the plugins will run and exit")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "create the projects inside of the given folder; may be a path template")
+	cmd.Flags().StringVar(&into, "into", "", "create the project inside of the given folder, which may already exist")
+	cmd.Flags().StringVar(&conflictStrategy, "conflict", conflictPrompt, "how to handle existing files when using --into (skip, overwrite, backup, prompt)")
+	cmd.Flags().IntVarP(&jobs, "jobs", "j", runtime.NumCPU(), "number of projects that are created at the same time")
+	_ = cmd.MarkFlagDirname("into")
+	_ = cmd.MarkFlagFilename("values", "toml", "json", "yaml", "yml")
+
 	return &projectCreateCommand{cmd: cmd}
 }
 
-// createProject is a small wrapper function which takes a project name, path and its associated package,
-// creates the project directory and tries to save it to storage.
-func createProject(name, path string, pkg *models.Package) error {
-	project := models.NewProject(name, path, pkg)
-	err := project.Create(activeSession.config.BasePath)
+// resolveProjectPath returns the absolute path of the project folder. The location template output takes precedence
+// over the projects root of the package. If neither is set, the project is created in the working directory. Relative
+// paths are relative to the working directory.
+func resolveProjectPath(
+	output, name string,
+	pkg *models.Package,
+	values render.Vars,
+	workingDirectory string,
+) (string, error) {
+	location := output
+	if location == "" {
+		location = pkg.ProjectsRoot
+	}
+	if location == "" {
+		return filepath.Join(workingDirectory, name), nil
+	}
+
+	path, err := render.Location(location, render.NewData(name, "", pkg.Name, pkg.Label, values))
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workingDirectory, path)
+	}
+	return path, nil
+}
+
+// loadPackages loads the packages with the given comma separated labels and composes them into one package; see
+// models.Compose. Templates that a later package replaces are reported as conflicts. A label may pin a version of the
+// package, e.g. go@1.2.0; the current version is loaded otherwise.
+func loadPackages(labels string) (*models.Package, error) {
+	packages := make([]*models.Package, 0)
+	loaded := make(map[string]bool)
+	for _, label := range strings.Split(labels, ",") {
+		label = strings.TrimSpace(label)
+		label, version := splitVersion(label)
+		if loaded[label] {
+			return nil, fmt.Errorf("package %s is listed more than once", label)
+		}
+		loaded[label] = true
+
+		var pkg *models.Package
+		var err error
+		if version == "" {
+			pkg, err = activeSession.storageService.LoadPackage(label)
+		} else {
+			pkg, err = activeSession.storageService.LoadPackageVersion(label, version)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load package %s", label)
+		}
+		packages = append(packages, pkg)
+	}
+	if len(packages) == 1 {
+		return packages[0], nil
+	}
+
+	pkg, conflicts := models.Compose(packages)
+	for _, conflict := range conflicts {
+		messages.Warningf("conflict: %s", conflict)
+	}
+	return pkg, nil
+}
+
+// splitVersion splits a label like go@1.2.0 into the label and the version. The version is empty if the label doesn't
+// pin one.
+func splitVersion(label string) (string, string) {
+	i := strings.LastIndex(label, "@")
+	if i < 0 {
+		return label, ""
+	}
+	return label[:i], label[i+1:]
+}
+
+// showPlan prints the resolved execution order of the package plugins and the point at which the templates get
+// created.
+func showPlan(label string) error {
+	pkg, err := loadPackages(label)
 	if err != nil {
-		return errors.Wrap(err, "failed to create project")
+		return err
+	}
+
+	planTable := util.NewInfoTable(os.Stdout)
+	planTable.SetTitle("PLAN")
+	planTable.AppendHeader(table.Row{"Stage", "Execution Number", "Plugin", "When"})
+	for _, plugin := range pkg.PrePlugins() {
+		planTable.AppendRow(table.Row{"pre", plugin.ExecNumber, plugin.Path, plugin.When})
+	}
+	planTable.AppendRow(table.Row{"templates", "", fmt.Sprintf("(%d templates)", len(pkg.Templates)), ""})
+	for _, plugin := range pkg.PostPlugins() {
+		planTable.AppendRow(table.Row{"post", plugin.ExecNumber, plugin.Path, plugin.When})
+	}
+	planTable.Render()
+	return nil
+}
+
+// createOptions control how projects are created.
+type createOptions struct {
+	atomic        bool                // Roll back failed creations.
+	allowExisting bool                // Create projects inside of existing folders.
+	conflict      render.ConflictFunc // Resolves conflicts with existing files.
+}
+
+// newProject returns a new project with the given name, path, package and values of the package variables that is
+// set up according to the options.
+func newProject(name, path string, pkg *models.Package, values render.Vars, options createOptions) *models.Project {
+	project := models.NewProject(name, path, pkg)
+	project.Variables = values
+	project.PluginTimeout = activeSession.config.Plugins.Timeout
+	project.AllowExisting = options.allowExisting
+	project.Conflict = options.conflict
+	return project
+}
+
+// createProjects creates the folders, files and plugins of the projects with up to jobs projects at a time. It returns
+// the error of every project in the order of the projects. Projects that didn't start before ctx was cancelled are not
+// created at all.
+func createProjects(ctx context.Context, projects []*models.Project, jobs int) []error {
+	errs := make([]error, len(projects))
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for worker := 0; worker < jobs && worker < len(projects); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				if ctx.Err() != nil {
+					errs[i] = errors.Wrap(ctx.Err(), "project creation aborted")
+					continue
+				}
+				messages.Infof("creating project %s", projects[i].Name)
+				errs[i] = projects[i].Create(ctx, activeSession.config.BasePath)
+			}
+		}()
+	}
+	for i := range projects {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+	return errs
+}
+
+// finishProject reports what happened during the creation of a project and saves the project to storage if it was
+// created without an error. If the atomic option is set, a failed creation is rolled back.
+func finishProject(project *models.Project, creationErr error, options createOptions) (err error) {
+	if options.atomic {
+		defer func() {
+			// A project that already exists in storage is not a failed creation; it may still be replaced.
+			if _, projectExists := err.(*storage.ProjectExistsError); err != nil && !projectExists {
+				rollbackProject(project)
+			}
+		}()
+	}
+
+	for _, skipped := range project.Skipped {
+		messages.Infof("skipped %s", skipped)
+	}
+	for _, backup := range project.Backups {
+		messages.Infof("backed up %s", backup)
+	}
+	for _, warning := range project.Warnings {
+		messages.Warningf("%s", warning)
+	}
+	if creationErr != nil {
+		return errors.Wrap(creationErr, "failed to create project")
 	}
 	err = activeSession.storageService.SaveProject(project)
+	if _, projectExists := err.(*storage.ProjectExistsError); projectExists {
+		return err
+	}
 	if err != nil {
 		return errors.Wrap(err, "failed to save project")
 	}
 	return nil
 }
 
+// showCreationSummary prints how many of the projects were created and which failed. Nothing is printed for a single
+// project, its result was already reported.
+func showCreationSummary(total int, failed []string) {
+	if total < 2 {
+		return
+	}
+	if len(failed) == 0 {
+		messages.Successf("created all %d projects", total)
+		return
+	}
+	messages.Warningf("created %d of %d projects, failed: %s", total-len(failed), total, strings.Join(failed, ", "))
+}
+
+// rollbackProject removes the folder and the storage record of a project whose creation failed, so that no partially
+// created project is left behind.
+func rollbackProject(project *models.Project) {
+	err := project.Rollback()
+	if err != nil {
+		messages.Warningf("failed to remove folder of project %s, %s", project.Name, err.Error())
+	}
+	if project.ID != 0 {
+		err = activeSession.storageService.PurgeProject(project.Path)
+		if err != nil {
+			messages.Warningf("failed to remove project %s from storage, %s", project.Name, err.Error())
+		}
+	}
+	messages.Infof("rolled back project %s", project.Name)
+}
+
 // replaceProject should usually be executed after a attempt to create a new project failed with an ProjectExistsError.
 // It will remove the given project from storage and save the new one, effectively replacing everything that's
 // associated with the given project path.
-func replaceProject(name, path string, pkg *models.Package) error {
-	err := activeSession.storageService.RemoveProject(path)
+func replaceProject(project *models.Project) error {
+	err := activeSession.storageService.RemoveProject(project.Path)
 	if err != nil {
 		return errors.Wrap(err, "failed to remove project")
 	}
-	project := models.NewProject(name, path, pkg)
-	err = activeSession.storageService.SaveProject(project)
+	replacement := models.NewProject(project.Name, project.Path, project.Package)
+	replacement.Manifest = project.Manifest
+	err = activeSession.storageService.SaveProject(replacement)
 	if err != nil {
 		return errors.Wrap(err, "failed to save project")
 	}
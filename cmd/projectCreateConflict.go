@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nikoksr/proji/diff"
+	"github.com/nikoksr/proji/render"
+	"github.com/nikoksr/proji/util"
+)
+
+// conflictPrompt is the conflict strategy that asks the user how to resolve every conflict.
+const conflictPrompt = "prompt"
+
+// conflictFunc returns the function that resolves conflicts with existing files according to the given strategy.
+func conflictFunc(strategy string) (render.ConflictFunc, error) {
+	if strategy == conflictPrompt {
+		if !activeSession.interactive {
+			return conflictFunc(render.ConflictSkip)
+		}
+		return newConflictPrompt(), nil
+	}
+	if !util.IsInSlice(render.ConflictStrategies(), strategy) {
+		return nil, fmt.Errorf(
+			"unknown conflict strategy '%s', expected one of %s, %s",
+			strategy,
+			strings.Join(render.ConflictStrategies(), ", "),
+			conflictPrompt,
+		)
+	}
+	return func(string, []byte, []byte) (string, error) {
+		return strategy, nil
+	}, nil
+}
+
+// newConflictPrompt returns a function that asks the user how to resolve a conflict with an existing file. The user
+// may look at the diff of the existing and the new file first and may choose to resolve all remaining conflicts the
+// same way.
+func newConflictPrompt() render.ConflictFunc {
+	answers := map[string]string{"s": render.ConflictSkip, "o": render.ConflictOverwrite, "b": render.ConflictBackup}
+	resolveAll := ""
+
+	return func(path string, current, content []byte) (string, error) {
+		if resolveAll != "" {
+			return resolveAll, nil
+		}
+		for {
+			input, err := util.Prompt(fmt.Sprintf(
+				"> %s already exists. [s]kip, [o]verwrite, [b]ackup or show [d]iff? Upper case applies to all files: ",
+				path,
+			))
+			if err != nil {
+				return "", err
+			}
+			input = strings.TrimSpace(input)
+			if resolution, ok := answers[strings.ToLower(input)]; ok {
+				if input != strings.ToLower(input) {
+					resolveAll = resolution
+				}
+				return resolution, nil
+			}
+			if strings.ToLower(input) == "d" {
+				showConflictDiff(path, current, content)
+			}
+		}
+	}
+}
+
+// showConflictDiff prints the diff of the existing file at path and the content that would replace it.
+func showConflictDiff(path string, current, content []byte) {
+	if render.IsBinary(current) || render.IsBinary(content) {
+		fmt.Printf("binary files %s and new %s differ\n", path, path)
+		return
+	}
+	fmt.Print(diff.Unified(path, path+" (new)", string(current), string(content), 3))
+}
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/nikoksr/proji/messages"
+	"github.com/nikoksr/proji/render"
+	"github.com/nikoksr/proji/storage/models"
+	"github.com/nikoksr/proji/util"
+)
+
+// showDryRun prints a tree of the files, folders and symlinks that creating the project would create, including the
+// templates they are rendered from, and the plugins that would run. If allowExisting is set, files that already exist
+// are marked. Nothing is written to disk or storage.
+func showDryRun(name, path string, pkg *models.Package, values render.Vars, allowExisting bool) error {
+	project := models.NewProject(name, path, pkg)
+	project.Variables = values
+	project.AllowExisting = allowExisting
+	plan, err := project.Plan(activeSession.config.BasePath)
+	if err != nil {
+		return err
+	}
+
+	messages.Infof("dry run of project %s, nothing will be created", name)
+	if !allowExisting && util.DoesPathExist(path) {
+		messages.Warningf("folder %s already exists, creating the project would fail", path)
+	}
+
+	tree := newFileTree()
+	pluginsTable := util.NewInfoTable(os.Stdout)
+	pluginsTable.SetTitle("PLUGINS")
+	pluginsTable.AppendHeader(table.Row{"Execution Number", "Plugin", "Type", "Args", "Skipped"})
+	templatesDir := filepath.Join(activeSession.config.BasePath, "templates")
+
+	for _, step := range plan.Steps {
+		skipped := ""
+		if step.Skipped {
+			skipped = "when " + step.Condition()
+		}
+		if step.Kind == models.StepPlugin {
+			pluginsTable.AppendRow(table.Row{
+				step.Plugin.ExecNumber,
+				step.Source,
+				step.Plugin.ResolvedType(),
+				strings.Join(step.Args, " "),
+				skipped,
+			})
+			continue
+		}
+		if step.Skipped {
+			messages.Infof("would skip template %s (when %s)", step.Template.Destination, step.Condition())
+			continue
+		}
+		err = addStepToTree(tree, step, templatesDir, project)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("\n%s/\n", path)
+	tree.print(os.Stdout, "")
+	fmt.Println()
+	if len(pkg.Plugins) > 0 {
+		pluginsTable.Render()
+	}
+	return nil
+}
+
+// addStepToTree adds the file, folder or symlink of a template step to the tree. Template folders are expanded to the
+// files and folders they contain. Files that already exist are marked if the project may be created inside of an
+// existing folder.
+func addStepToTree(tree *fileTree, step *models.Step, templatesDir string, project *models.Project) error {
+	add := func(path string, isDir bool, note string) {
+		if project.AllowExisting && !isDir && util.DoesPathExist(filepath.Join(project.Path, path)) {
+			note += " (exists)"
+		}
+		tree.add(path, isDir, note)
+	}
+
+	mode := ""
+	if step.Template.Mode != "" {
+		mode = " [" + step.Template.Mode + "]"
+	}
+
+	switch {
+	case step.Kind == models.StepSymlink:
+		add(step.Destination, false, "-> "+step.Source)
+		return nil
+	case len(step.Template.Content) > 0:
+		add(step.Destination, false, "<- (inline)"+mode)
+		return nil
+	case len(step.Source) == 0:
+		add(step.Destination, step.Kind == models.StepFolder, "(empty)"+mode)
+		return nil
+	}
+
+	info, err := os.Stat(step.Source)
+	if err != nil {
+		add(step.Destination, step.Kind == models.StepFolder, "<- "+step.Template.Path+" (missing)")
+		return nil
+	}
+	add(step.Destination, info.IsDir(), "<- "+step.Template.Path+mode)
+	if !info.IsDir() {
+		return nil
+	}
+
+	data := project.TemplateData()
+	if step.Template.Raw {
+		data = nil
+	}
+	entries, err := render.Entries(step.Source, data)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		source, _ := filepath.Rel(templatesDir, filepath.Join(step.Source, entry.Source))
+		note := "<- " + source
+		if entry.IsSymlink {
+			note = "(symlink)"
+		}
+		add(filepath.Join(step.Destination, entry.Path), entry.IsDir, note)
+	}
+	return nil
+}
+
+// fileTree is a tree of paths that keeps the order in which the paths were added.
+type fileTree struct {
+	name     string
+	note     string
+	isDir    bool
+	children []*fileTree
+}
+
+func newFileTree() *fileTree {
+	return &fileTree{isDir: true}
+}
+
+// add adds the path to the tree. Missing parent folders are added without a note.
+func (t *fileTree) add(path string, isDir bool, note string) {
+	node := t
+	for _, name := range strings.Split(filepath.Clean(path), string(filepath.Separator)) {
+		node = node.child(name)
+	}
+	node.isDir = node.isDir || isDir
+	node.note = note
+}
+
+// child returns the child with the given name. The child is created if it doesn't exist yet. Parents are always
+// folders.
+func (t *fileTree) child(name string) *fileTree {
+	t.isDir = true
+	for _, child := range t.children {
+		if child.name == name {
+			return child
+		}
+	}
+	child := &fileTree{name: name}
+	t.children = append(t.children, child)
+	return child
+}
+
+// print prints the children of the tree, each prefixed with prefix and the branch symbols.
+func (t *fileTree) print(out io.Writer, prefix string) {
+	for i, child := range t.children {
+		branch, indent := "├── ", "│   "
+		if i == len(t.children)-1 {
+			branch, indent = "└── ", "    "
+		}
+		name := child.name
+		if child.isDir {
+			name += "/"
+		}
+		if child.note != "" {
+			name += "  " + child.note
+		}
+		fmt.Fprintf(out, "%s%s%s\n", prefix, branch, name)
+		child.print(out, prefix+indent)
+	}
+}
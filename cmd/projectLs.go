@@ -1,7 +1,9 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/nikoksr/proji/util"
 	"github.com/pkg/errors"
@@ -37,10 +39,17 @@ func listProjects() error {
 	projectsTable.AppendHeader(table.Row{"Name", "Install Path", "Package"})
 
 	for _, project := range projects {
+		packageName := "(removed)"
+		if project.Package != nil {
+			packageName = project.Package.Name
+		}
+		if len(project.Missing) > 0 {
+			packageName += fmt.Sprintf(" (removed: %s)", strings.Join(project.Missing, ", "))
+		}
 		projectsTable.AppendRow(table.Row{
 			project.Name,
 			project.Path,
-			project.Package.Name,
+			packageName,
 		})
 	}
 
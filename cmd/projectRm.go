@@ -6,7 +6,6 @@ import (
 	"github.com/nikoksr/proji/messages"
 
 	"github.com/nikoksr/proji/storage/models"
-	"github.com/nikoksr/proji/util"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
@@ -49,7 +48,7 @@ func newProjectRemoveCommand() *projectRemoveCommand {
 			for _, project := range projects {
 				// Ask for confirmation if force flag was not passed
 				if !forceRemoveProjects {
-					if !util.WantTo(
+					if !confirm(
 						fmt.Sprintf("Do you really want to remove the path %s from your projects?", project.Path),
 					) {
 						continue
@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/nikoksr/proji/diff"
+	"github.com/nikoksr/proji/messages"
+	"github.com/nikoksr/proji/storage/models"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type projectUpdateCommand struct {
+	cmd *cobra.Command
+}
+
+func newProjectUpdateCommand() *projectUpdateCommand {
+	var dryRun bool
+	var valuesFile string
+	var setValues []string
+
+	var cmd = &cobra.Command{
+		Use:   "update [PATH...]",
+		Short: "Re-apply the package of projects",
+		Long: `Re-apply the current version of the package that a project was created from. Defaults to the project in the
+current working directory.
+
+proji keeps a snapshot of the files it generated in the .proji folder of every project. On update, the package is
+rendered again and compared with that snapshot and with the files of the project:
+
+  - files that only changed in the package are replaced
+  - files that changed in the package and in the project are merged line by line; conflicting changes are marked
+    with conflict markers like git does and have to be resolved by hand
+  - files that were removed from the package are removed if they weren't changed in the project
+  - files that were changed or deleted in the project are left untouched otherwise
+
+The changes are shown as a diff and applied after confirmation. The variables keep the values the project was created
+with unless they are set with --set or --values. Plugins are not run again. Projects that were created before proji
+kept snapshots have no common base to merge with; files that differ are left untouched and listed.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				args = []string{"."}
+			}
+			presets, err := loadPresetValues(valuesFile, setValues)
+			if err != nil {
+				return errors.Wrap(err, "failed to load variable values")
+			}
+
+			failed := 0
+			for _, path := range args {
+				err = updateProject(path, presets, dryRun)
+				if err != nil {
+					messages.Warningf("failed to update project at %s, %s", path, err.Error())
+					failed++
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("failed to update %d of %d projects", failed, len(args))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "show the changes without applying them")
+	cmd.Flags().StringArrayVar(&setValues, "set", make([]string, 0), "set a variable value (key=value); can be repeated")
+	cmd.Flags().StringVar(&valuesFile, "values", "", "load variable values from a toml, json or yaml file")
+	_ = cmd.MarkFlagFilename("values", "toml", "json", "yaml", "yml")
+
+	return &projectUpdateCommand{cmd: cmd}
+}
+
+// updateProject re-applies the package of the project at path. Presets override the values that the project was created
+// with.
+func updateProject(path string, presets map[string]string, dryRun bool) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	project, err := activeSession.storageService.LoadProject(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to load project")
+	}
+	if project.Package == nil || project.Package.ID == 0 {
+		return fmt.Errorf("package of project %s not found", project.Name)
+	}
+	// Updating without them would remove the files that the missing packages generated
+	if len(project.Missing) > 0 {
+		return fmt.Errorf("packages %s of project %s not found", strings.Join(project.Missing, ", "), project.Name)
+	}
+
+	err = resolveProjectVariables(project, presets)
+	if err != nil {
+		return err
+	}
+
+	update, err := project.PrepareUpdate(activeSession.config.BasePath)
+	if err != nil {
+		return err
+	}
+	showUpdate(project, update)
+	if !update.HasChanges() {
+		messages.Successf("project %s is up to date with package %s", project.Name, strings.Join(project.Package.Labels(), ","))
+		if dryRun {
+			return nil
+		}
+		// Refresh the snapshot and the manifest anyway; they might be missing or outdated
+		return applyUpdate(project, update)
+	}
+	if dryRun || !confirm(fmt.Sprintf("> Apply the changes to project %s?", project.Name)) {
+		return nil
+	}
+
+	err = applyUpdate(project, update)
+	if err != nil {
+		return err
+	}
+	for _, file := range update.Files {
+		if file.Action == models.UpdateConflict {
+			messages.Warningf("resolve the conflicts in %s", file.Path)
+		}
+	}
+	messages.Successf("successfully updated project %s", project.Name)
+	return nil
+}
+
+// resolveProjectVariables sets the variables of a project that was loaded from storage. The values that the project was
+// created with are used unless presets override them.
+func resolveProjectVariables(project *models.Project, presets map[string]string) error {
+	stored, err := project.LoadValues()
+	if err != nil {
+		return errors.Wrap(err, "failed to load variable values of project")
+	}
+	values := make(map[string]string, len(stored)+len(presets))
+	for name, value := range stored {
+		values[strings.ToLower(name)] = value
+	}
+	for name, value := range presets {
+		values[name] = value
+	}
+	project.Variables, err = resolveVariables(project.Package.Variables, values)
+	if err != nil {
+		return errors.Wrap(err, "failed to resolve variables")
+	}
+	return nil
+}
+
+// applyUpdate applies the update to the project and saves the new manifest of the project to storage.
+func applyUpdate(project *models.Project, update *models.Update) error {
+	err := update.Apply()
+	if err != nil {
+		return err
+	}
+	err = activeSession.storageService.UpdateProjectManifest(project)
+	if err != nil {
+		return errors.Wrap(err, "failed to save manifest")
+	}
+	return nil
+}
+
+// showUpdate prints the changes of an update as diffs and lists the files that are left untouched.
+func showUpdate(project *models.Project, update *models.Update) {
+	messages.Infof("updating project %s at %s from package %s", project.Name, project.Path, strings.Join(project.Package.Labels(), ","))
+	for _, conflict := range project.Conflicts {
+		messages.Warningf("conflict: %s", conflict)
+	}
+	for _, file := range update.Files {
+		switch {
+		case file.Action == models.UpdateKeep:
+			messages.Warningf("keeping %s, %s", file.Path, file.Reason)
+		case file.IsDir:
+			fmt.Printf("%s folder %s\n", file.Action, file.Path)
+		case file.IsSymlink:
+			fmt.Printf("%s symlink %s -> %s\n", file.Action, file.Path, file.Content)
+		case file.Binary:
+			fmt.Printf("%s binary file %s\n", file.Action, file.Path)
+		case file.Action == models.UpdateRemove:
+			fmt.Printf("%s %s\n", file.Action, file.Path)
+			fmt.Print(diff.Unified(file.Path, "/dev/null", string(file.Current), "", 3))
+		default:
+			fmt.Printf("%s %s\n", file.Action, file.Path)
+			fmt.Print(diff.Unified(file.Path, file.Path+" (new)", string(file.Current), string(file.Content), 3))
+		}
+	}
+}
@@ -1,13 +1,17 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/nikoksr/proji/messages"
 
 	"github.com/nikoksr/proji/config"
 	"github.com/nikoksr/proji/storage"
+	"github.com/nikoksr/proji/util"
 	"github.com/spf13/cobra"
 	"golang.org/x/crypto/ssh/terminal"
 )
@@ -23,6 +27,8 @@ type session struct {
 	version             string
 	noColors            bool
 	maxTableColumnWidth int
+	assumeYes           bool // Answer all confirmations with yes.
+	interactive         bool // Whether proji may prompt the user for input.
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -31,6 +37,7 @@ func Execute() {
 	err := newRootCommand().cmd.Execute()
 	if err != nil {
 		messages.Errorf("", err)
+		os.Exit(1)
 	}
 }
 
@@ -39,7 +46,7 @@ type rootCommand struct {
 }
 
 func newRootCommand() *rootCommand {
-	var disableColors bool
+	var disableColors, assumeYes, noInput bool
 
 	var cmd = &cobra.Command{
 		Use:           "proji",
@@ -56,20 +63,29 @@ func newRootCommand() *rootCommand {
 
 			// Prepare proji
 			prepare()
+
+			// Only prompt for input if it was not disabled and stdin is a terminal
+			activeSession.assumeYes = assumeYes
+			activeSession.interactive = !assumeYes && !noInput && terminal.IsTerminal(int(os.Stdin.Fd()))
 		},
 	}
 
 	cmd.PersistentFlags().BoolVar(&disableColors, "no-colors", false, "disable text colors")
+	cmd.PersistentFlags().BoolVarP(&assumeYes, "yes", "y", false, "answer all confirmations with yes and never prompt for input")
+	cmd.PersistentFlags().BoolVar(&noInput, "no-input", false, "never prompt for input; fail if required values are missing")
 	cmd.AddCommand(
 		newCompletionCommand().cmd,
 		newInitCommand().cmd,
 		newPackageCommand().cmd,
+		newPluginCommand().cmd,
 		newProjectAddCommand().cmd,
+		newProjectCheckCommand().cmd,
 		newProjectCleanCommand().cmd,
 		newProjectCreateCommand().cmd,
 		newProjectListCommand().cmd,
 		newProjectRemoveCommand().cmd,
 		newProjectSetCommand().cmd,
+		newProjectUpdateCommand().cmd,
 		newVersionCommand().cmd,
 	)
 	return &rootCommand{cmd: cmd}
@@ -151,6 +167,38 @@ func initStorageService() {
 	}
 }
 
+// confirm asks the user to confirm the given question. If proji runs non-interactively, the question is answered with
+// yes if the yes flag was passed and with no otherwise.
+func confirm(question string) bool {
+	if activeSession.assumeYes {
+		return true
+	}
+	if !activeSession.interactive {
+		return false
+	}
+	return util.WantTo(question)
+}
+
+// interruptContext returns a context that is cancelled when proji receives an interrupt or termination signal. The
+// returned function stops listening for signals and has to be called once the context is no longer needed.
+func interruptContext() (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-signals:
+			messages.Warningf("interrupted, aborting")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
+
 func getTerminalWidth() (int, error) {
 	w, _, err := terminal.GetSize(int(os.Stdout.Fd()))
 	if err != nil {
@@ -160,6 +208,11 @@ func getTerminalWidth() (int, error) {
 }
 
 func getMaxColumnWidth() int {
+	// Output that is not written to a terminal has no width; don't clutter it, e.g. json output, with a warning
+	if !terminal.IsTerminal(int(os.Stdout.Fd())) {
+		return 50
+	}
+
 	//Load terminal width and set max column width for dynamic rendering
 	terminalWidth, err := getTerminalWidth()
 	if err != nil {
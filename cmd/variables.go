@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nikoksr/proji/messages"
+	"github.com/nikoksr/proji/render"
+	"github.com/nikoksr/proji/storage/models"
+	"github.com/nikoksr/proji/util"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// loadPresetValues loads variable values from the given values file and key=value pairs. Values of the key=value pairs
+// take precedence over values from the file. Keys are lower-cased because the values file keys are case-insensitive.
+func loadPresetValues(valuesFile string, setValues []string) (map[string]string, error) {
+	presets := make(map[string]string)
+
+	if valuesFile != "" {
+		provider := viper.New()
+		provider.SetConfigFile(valuesFile)
+		err := provider.ReadInConfig()
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range provider.AllSettings() {
+			presets[strings.ToLower(key)] = fmt.Sprint(value)
+		}
+	}
+
+	for _, pair := range setValues {
+		keyValue := strings.SplitN(pair, "=", 2)
+		if len(keyValue) != 2 || strings.TrimSpace(keyValue[0]) == "" {
+			return nil, fmt.Errorf("invalid value '%s', expected format key=value", pair)
+		}
+		presets[strings.ToLower(strings.TrimSpace(keyValue[0]))] = keyValue[1]
+	}
+	return presets, nil
+}
+
+// warnUnknownPresets prints a warning for every preset value that doesn't belong to any of the given variables.
+func warnUnknownPresets(variables []*models.Variable, presets map[string]string) {
+	for key := range presets {
+		known := false
+		for _, variable := range variables {
+			if strings.ToLower(variable.Name) == key {
+				known = true
+				break
+			}
+		}
+		if !known {
+			messages.Warningf("ignoring value for unknown variable %s", key)
+		}
+	}
+}
+
+// resolveVariables determines the values of the given variables. Preset values are used if available. Otherwise the
+// user is asked for a value if proji runs interactively, else the default value is used. Returns an error listing
+// all variables for which no value could be determined.
+func resolveVariables(variables []*models.Variable, presets map[string]string) (render.Vars, error) {
+	values := make(render.Vars, len(variables))
+	missing := make([]string, 0)
+
+	for _, variable := range variables {
+		if preset, ok := presets[strings.ToLower(variable.Name)]; ok {
+			value, err := variable.Parse(preset)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid value for variable %s", variable.Name)
+			}
+			values[variable.Name] = value
+			continue
+		}
+
+		if activeSession.interactive {
+			value, err := promptVariable(variable)
+			if err != nil {
+				return nil, err
+			}
+			values[variable.Name] = value
+			continue
+		}
+
+		if variable.IsRequired() {
+			missing = append(missing, variable.Name)
+			continue
+		}
+		value, err := variable.Parse("")
+		if err != nil {
+			return nil, err
+		}
+		values[variable.Name] = value
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing values for required variables: %s", strings.Join(missing, ", "))
+	}
+	return values, nil
+}
+
+// promptVariable asks the user for a value of the given variable. Invalid inputs are rejected and asked for again.
+func promptVariable(variable *models.Variable) (interface{}, error) {
+	for {
+		input, err := util.Prompt(variable.Question())
+		if err != nil {
+			return nil, err
+		}
+		value, err := variable.Parse(input)
+		if err == nil {
+			return value, nil
+		}
+		messages.Warningf("invalid value for %s, %s", variable.Name, err.Error())
+	}
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nikoksr/proji/render"
+	"github.com/nikoksr/proji/storage/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadPresetValues(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "proji-cmd")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+	values := map[string]string{
+		"values.toml": "license = \"GPL\"\nPort = 9000\n",
+		"values.json": "{\"license\": \"GPL\", \"port\": 9000}",
+		"values.yaml": "license: GPL\nport: 9000\n",
+		"values.txt":  "license GPL",
+	}
+	for name, content := range values {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644))
+	}
+
+	tests := []struct {
+		name       string
+		valuesFile string
+		setValues  []string
+		want       map[string]string
+		wantErr    bool
+	}{
+		{name: "Nothing", want: map[string]string{}},
+		{
+			name:      "Set",
+			setValues: []string{"License=MIT", "name = a=b"},
+			want:      map[string]string{"license": "MIT", "name": " a=b"},
+		},
+		{name: "TOML file", valuesFile: "values.toml", want: map[string]string{"license": "GPL", "port": "9000"}},
+		{name: "JSON file", valuesFile: "values.json", want: map[string]string{"license": "GPL", "port": "9000"}},
+		{name: "YAML file", valuesFile: "values.yaml", want: map[string]string{"license": "GPL", "port": "9000"}},
+		{
+			name:       "Set overrides file",
+			valuesFile: "values.toml",
+			setValues:  []string{"port=8000"},
+			want:       map[string]string{"license": "GPL", "port": "8000"},
+		},
+		{name: "Unsupported file", valuesFile: "values.txt", wantErr: true},
+		{name: "Missing file", valuesFile: "missing.toml", wantErr: true},
+		{name: "Missing separator", setValues: []string{"license"}, wantErr: true},
+		{name: "Missing key", setValues: []string{"=MIT"}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		valuesFile := test.valuesFile
+		if valuesFile != "" {
+			valuesFile = filepath.Join(tmpDir, valuesFile)
+		}
+		got, err := loadPresetValues(valuesFile, test.setValues)
+		if test.wantErr {
+			assert.Error(t, err, test.name)
+			continue
+		}
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.want, got, test.name)
+	}
+}
+
+func TestResolveVariables(t *testing.T) {
+	previous := activeSession
+	activeSession = &session{interactive: false}
+	defer func() { activeSession = previous }()
+
+	variables := []*models.Variable{
+		{Name: "license", Type: models.VariableTypeChoice, Choices: models.StringList{"MIT", "GPL"}, Default: "MIT"},
+		{Name: "Port", Type: models.VariableTypeInt, Default: "8080"},
+		{Name: "author", Type: models.VariableTypeString},
+	}
+
+	tests := []struct {
+		name    string
+		presets map[string]string
+		want    render.Vars
+		wantErr bool
+	}{
+		{
+			name:    "Defaults",
+			presets: map[string]string{"author": "nikoksr"},
+			want:    render.Vars{"license": "MIT", "Port": 8080, "author": "nikoksr"},
+		},
+		{
+			name:    "Presets override defaults",
+			presets: map[string]string{"author": "nikoksr", "license": "GPL", "port": "9000"},
+			want:    render.Vars{"license": "GPL", "Port": 9000, "author": "nikoksr"},
+		},
+		{name: "Missing required value", presets: map[string]string{"license": "GPL"}, wantErr: true},
+		{name: "Empty required value", presets: map[string]string{"author": ""}, wantErr: true},
+		{name: "Invalid preset", presets: map[string]string{"author": "nikoksr", "port": "http"}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := resolveVariables(variables, test.presets)
+		if test.wantErr {
+			assert.Error(t, err, test.name)
+			continue
+		}
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.want, got, test.name)
+	}
+}
+
+func TestResolveVariablesFromFileAndFlags(t *testing.T) {
+	previous := activeSession
+	activeSession = &session{interactive: false}
+	defer func() { activeSession = previous }()
+
+	tmpDir, err := ioutil.TempDir("", "proji-cmd")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+	valuesFile := filepath.Join(tmpDir, "values.toml")
+	assert.NoError(t, ioutil.WriteFile(valuesFile, []byte("license = \"GPL\"\nauthor = \"file\"\n"), 0644))
+
+	variables := []*models.Variable{
+		{Name: "license", Type: models.VariableTypeChoice, Choices: models.StringList{"MIT", "GPL"}, Default: "MIT"},
+		{Name: "author", Type: models.VariableTypeString, Default: "default"},
+		{Name: "year", Type: models.VariableTypeInt, Default: "2020"},
+	}
+	presets, err := loadPresetValues(valuesFile, []string{"author=flag"})
+	assert.NoError(t, err)
+	got, err := resolveVariables(variables, presets)
+	assert.NoError(t, err)
+	assert.Equal(t, render.Vars{"license": "GPL", "author": "flag", "year": 2020}, got)
+}
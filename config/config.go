@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -22,18 +23,25 @@ type DatabaseConnection struct {
 	DSN    string `mapstructure:"dsn"`
 }
 
+// PluginSettings represents the configurable and plugin execution related values in the main config.
+type PluginSettings struct {
+	Timeout time.Duration `mapstructure:"timeout"` // Default timeout of a plugin; zero disables the timeout.
+}
+
 // Config represents central resources and information the app uses.
 type Config struct {
 	Auth               *APIAuthentication  `mapstructure:"auth"`
 	BasePath           string              `mapstructure:"-"`
 	DatabaseConnection *DatabaseConnection `mapstructure:"database"`
 	ExcludedPaths      []string            `mapstructure:"import.exclude_folders"`
+	Plugins            *PluginSettings     `mapstructure:"plugins"`
 	provider           *viper.Viper        `mapstructure:"-"`
 }
 
 const (
 	defaultDatabaseDriver = "sqlite3"
 	defaultDatabaseDSN    = "/db/proji.sqlite3"
+	defaultPluginTimeout  = "10m"
 )
 
 //nolint:gochecknoglobals
@@ -103,6 +111,7 @@ func (c *Config) setDefaultValues() {
 	c.provider.SetDefault("import.exclude_folders", []string{})
 	c.provider.SetDefault("database.driver", defaultDatabaseDriver)
 	c.provider.SetDefault("database.dsn", filepath.Join(c.BasePath, defaultDatabaseDSN))
+	c.provider.SetDefault("plugins.timeout", defaultPluginTimeout)
 }
 
 // set should run after loadFile and loadEnvironmentVariables. It sets the loaded values as the final config.
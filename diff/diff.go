@@ -0,0 +1,217 @@
+// Package diff implements a line based diff of two texts and prints it in the unified format.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Kinds of edits.
+const (
+	Equal  = ' ' // Line is part of both texts.
+	Delete = '-' // Line is only part of the old text.
+	Insert = '+' // Line is only part of the new text.
+)
+
+// Edit is a single line of a diff.
+type Edit struct {
+	Kind rune
+	Line string
+}
+
+// Lines returns the shortest list of edits that turns the lines a into the lines b. It implements the algorithm of
+// Eugene W. Myers, "An O(ND) Difference Algorithm and Its Variations".
+func Lines(a, b []string) []Edit {
+	// Lines that both texts start or end with are equal; only the middle part has to be diffed.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	edits := make([]Edit, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		edits = append(edits, Edit{Kind: Equal, Line: line})
+	}
+	edits = append(edits, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		edits = append(edits, Edit{Kind: Equal, Line: line})
+	}
+	return edits
+}
+
+// myers returns the edits that turn a into b.
+func myers(a, b []string) []Edit {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+
+	// Remember the furthest reaching paths of every round to be able to walk back the shortest path
+	trace := make([][]int, 0)
+	for d := 0; d <= n+m; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace, offset)
+			}
+		}
+	}
+	return nil
+}
+
+// backtrack walks the shortest path found by myers back from its end and returns its edits.
+func backtrack(a, b []string, trace [][]int, offset int) []Edit {
+	x, y := len(a), len(b)
+	reversed := make([]Edit, 0, x+y)
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			reversed = append(reversed, Edit{Kind: Equal, Line: a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, Edit{Kind: Insert, Line: b[y-1]})
+			} else {
+				reversed = append(reversed, Edit{Kind: Delete, Line: a[x-1]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	edits := make([]Edit, len(reversed))
+	for i, edit := range reversed {
+		edits[len(reversed)-1-i] = edit
+	}
+	return edits
+}
+
+// SplitLines splits the text into its lines. A trailing newline does not start another line.
+func SplitLines(text string) []string {
+	if text == "" {
+		return []string{}
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// Unified returns the diff of the texts oldText and newText in the unified format with the given number of context
+// lines around every change. The names are used in the header. An empty string is returned if the texts are equal.
+func Unified(oldName, newName, oldText, newText string, context int) string {
+	edits := Lines(SplitLines(oldText), SplitLines(newText))
+
+	var sb strings.Builder
+	for _, h := range hunks(edits, context) {
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(h.oldStart, h.oldLines), hunkRange(h.newStart, h.newLines))
+		for _, edit := range edits[h.start:h.end] {
+			fmt.Fprintf(&sb, "%c%s\n", edit.Kind, edit.Line)
+		}
+	}
+	return sb.String()
+}
+
+// hunk is a group of edits that are close to each other.
+type hunk struct {
+	start, end         int // Range of the edits.
+	oldStart, oldLines int
+	newStart, newLines int
+}
+
+// hunks groups the changes of edits into hunks with up to context equal lines before and after every change. Hunks
+// whose context would overlap are merged.
+func hunks(edits []Edit, context int) []hunk {
+	result := make([]hunk, 0)
+	oldLine, newLine := 0, 0
+	lastChange := -1
+
+	for i, edit := range edits {
+		if edit.Kind != Equal {
+			if len(result) == 0 || i-lastChange-1 > 2*context {
+				start := i - context
+				if start < 0 {
+					start = 0
+				}
+				result = append(result, hunk{start: start, oldStart: oldLine - (i - start), newStart: newLine - (i - start)})
+			}
+			lastChange = i
+		}
+		switch edit.Kind {
+		case Delete:
+			oldLine++
+		case Insert:
+			newLine++
+		default:
+			oldLine++
+			newLine++
+		}
+	}
+
+	for i := range result {
+		end := len(edits)
+		if i+1 < len(result) {
+			end = result[i+1].start
+		}
+		result[i].end = end
+	}
+	// Cut the trailing context of every hunk down to the given number of lines
+	for i := range result {
+		h := &result[i]
+		lastChange := h.start
+		for j := h.start; j < h.end; j++ {
+			if edits[j].Kind != Equal {
+				lastChange = j
+			}
+		}
+		if lastChange+1+context < h.end {
+			h.end = lastChange + 1 + context
+		}
+		for _, edit := range edits[h.start:h.end] {
+			if edit.Kind != Insert {
+				h.oldLines++
+			}
+			if edit.Kind != Delete {
+				h.newLines++
+			}
+		}
+	}
+	return result
+}
+
+// hunkRange formats the line range of a hunk. Line numbers start at one; empty ranges refer to the line before.
+func hunkRange(start, lines int) string {
+	if lines == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if lines == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, lines)
+}
@@ -0,0 +1,154 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// apply applies the edits and returns the old and the new lines.
+func apply(edits []Edit) ([]string, []string) {
+	a, b := make([]string, 0), make([]string, 0)
+	for _, edit := range edits {
+		if edit.Kind != Insert {
+			a = append(a, edit.Line)
+		}
+		if edit.Kind != Delete {
+			b = append(b, edit.Line)
+		}
+	}
+	return a, b
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		changes int
+	}{
+		{name: "Equal", a: "a b c", b: "a b c", changes: 0},
+		{name: "Both empty", a: "", b: "", changes: 0},
+		{name: "Insert into empty", a: "", b: "a b", changes: 2},
+		{name: "Delete all", a: "a b", b: "", changes: 2},
+		{name: "Insert in the middle", a: "a c", b: "a b c", changes: 1},
+		{name: "Delete in the middle", a: "a b c", b: "a c", changes: 1},
+		{name: "Replace", a: "a b c", b: "a x c", changes: 2},
+		{name: "Myers example", a: "a b c a b b a", b: "c b a b a c", changes: 5},
+	}
+
+	for _, test := range tests {
+		a, b := strings.Fields(test.a), strings.Fields(test.b)
+		edits := Lines(a, b)
+
+		gotA, gotB := apply(edits)
+		assert.Equal(t, a, gotA, test.name)
+		assert.Equal(t, b, gotB, test.name)
+
+		changes := 0
+		for _, edit := range edits {
+			if edit.Kind != Equal {
+				changes++
+			}
+		}
+		assert.Equal(t, test.changes, changes, test.name)
+	}
+}
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name    string
+		old     string
+		new     string
+		context int
+		want    string
+	}{
+		{name: "Equal", old: "a\nb\n", new: "a\nb\n", context: 3, want: ""},
+		{
+			name:    "Single change",
+			old:     "a\nb\nc\n",
+			new:     "a\nx\nc\n",
+			context: 3,
+			want:    "--- old\n+++ new\n@@ -1,3 +1,3 @@\n a\n-b\n+x\n c\n",
+		},
+		{
+			name:    "Limited context",
+			old:     "1\n2\n3\n4\n5\n6\n7\n",
+			new:     "1\n2\n3\nx\n5\n6\n7\n",
+			context: 1,
+			want:    "--- old\n+++ new\n@@ -3,3 +3,3 @@\n 3\n-4\n+x\n 5\n",
+		},
+		{
+			name:    "Separate hunks",
+			old:     "1\n2\n3\n4\n5\n6\n7\n",
+			new:     "x\n2\n3\n4\n5\n6\ny\n",
+			context: 1,
+			want:    "--- old\n+++ new\n@@ -1,2 +1,2 @@\n-1\n+x\n 2\n@@ -6,2 +6,2 @@\n 6\n-7\n+y\n",
+		},
+		{
+			name:    "Merged hunks",
+			old:     "1\n2\n3\n4\n",
+			new:     "x\n2\n3\ny\n",
+			context: 1,
+			want:    "--- old\n+++ new\n@@ -1,4 +1,4 @@\n-1\n+x\n 2\n 3\n-4\n+y\n",
+		},
+		{
+			name:    "New file",
+			old:     "",
+			new:     "a\n",
+			context: 3,
+			want:    "--- old\n+++ new\n@@ -0,0 +1 @@\n+a\n",
+		},
+	}
+
+	for _, test := range tests {
+		got := Unified("old", "new", test.old, test.new, test.context)
+		assert.Equal(t, test.want, got, test.name)
+	}
+}
+
+func TestMergeText(t *testing.T) {
+	tests := []struct {
+		name      string
+		base      string
+		ours      string
+		theirs    string
+		want      string
+		conflicts int
+	}{
+		{name: "Unchanged", base: "a\nb\n", ours: "a\nb\n", theirs: "a\nb\n", want: "a\nb\n"},
+		{name: "Only ours", base: "a\nb\nc\n", ours: "a\nx\nc\n", theirs: "a\nb\nc\n", want: "a\nx\nc\n"},
+		{name: "Only theirs", base: "a\nb\nc\n", ours: "a\nb\nc\n", theirs: "a\nb\nc\nd\n", want: "a\nb\nc\nd\n"},
+		{
+			name:   "Both in different places",
+			base:   "1\n2\n3\n4\n5\n",
+			ours:   "x\n2\n3\n4\n5\n",
+			theirs: "1\n2\n3\n4\ny\n",
+			want:   "x\n2\n3\n4\ny\n",
+		},
+		{name: "Same change on both sides", base: "a\nb\n", ours: "a\nx\n", theirs: "a\nx\n", want: "a\nx\n"},
+		{
+			name:      "Conflict",
+			base:      "a\nb\nc\n",
+			ours:      "a\nx\nc\n",
+			theirs:    "a\ny\nc\n",
+			want:      "a\n<<<<<<< ours\nx\n=======\ny\n>>>>>>> theirs\nc\n",
+			conflicts: 1,
+		},
+		{
+			name:      "Insertions at the same place",
+			base:      "a\n",
+			ours:      "a\nx\n",
+			theirs:    "a\ny\n",
+			want:      "a\n<<<<<<< ours\nx\n=======\ny\n>>>>>>> theirs\n",
+			conflicts: 1,
+		},
+		{name: "Deleted on our side", base: "a\nb\nc\n", ours: "a\nc\n", theirs: "a\nb\nc\nd\n", want: "a\nc\nd\n"},
+	}
+
+	for _, test := range tests {
+		got, conflicts := MergeText(test.base, test.ours, test.theirs, "ours", "theirs")
+		assert.Equal(t, test.want, got, test.name)
+		assert.Equal(t, test.conflicts, conflicts, test.name)
+	}
+}
@@ -0,0 +1,139 @@
+package diff
+
+import "strings"
+
+// change replaces the lines start to end of a base text with lines.
+type change struct {
+	start, end int
+	lines      []string
+}
+
+// changes returns the changes that turn the lines base into the lines other.
+func changes(base, other []string) []change {
+	result := make([]change, 0)
+	var current *change
+	line := 0
+	for _, edit := range Lines(base, other) {
+		if edit.Kind == Equal {
+			if current != nil {
+				result = append(result, *current)
+				current = nil
+			}
+			line++
+			continue
+		}
+		if current == nil {
+			current = &change{start: line, end: line}
+		}
+		if edit.Kind == Delete {
+			current.end++
+			line++
+		} else {
+			current.lines = append(current.lines, edit.Line)
+		}
+	}
+	if current != nil {
+		result = append(result, *current)
+	}
+	return result
+}
+
+// applyChanges applies the changes to the lines start to end of base.
+func applyChanges(base []string, start, end int, changes []change) []string {
+	result := make([]string, 0)
+	line := start
+	for _, c := range changes {
+		result = append(result, base[line:c.start]...)
+		result = append(result, c.lines...)
+		line = c.end
+	}
+	return append(result, base[line:end]...)
+}
+
+// Merge3 merges the changes that were made to the lines base in ours and in theirs. Changes that overlap or touch each
+// other conflict unless they are equal. Conflicting parts are marked with conflict markers in the style of git,
+// labelled with the given names. It returns the merged lines and the number of conflicts.
+func Merge3(base, ours, theirs []string, oursName, theirsName string) ([]string, int) {
+	oursChanges, theirsChanges := changes(base, ours), changes(base, theirs)
+	result := make([]string, 0, len(base))
+	conflicts := 0
+	line := 0
+
+	for len(oursChanges) > 0 || len(theirsChanges) > 0 {
+		// Start the next group with the change that comes first and add all changes of both sides that overlap or
+		// touch the group
+		var start, end int
+		if len(theirsChanges) == 0 || len(oursChanges) > 0 && oursChanges[0].start <= theirsChanges[0].start {
+			start, end = oursChanges[0].start, oursChanges[0].end
+		} else {
+			start, end = theirsChanges[0].start, theirsChanges[0].end
+		}
+		var oursGroup, theirsGroup []change
+		for {
+			if len(oursChanges) > 0 && oursChanges[0].start <= end {
+				oursGroup = append(oursGroup, oursChanges[0])
+				end = maxInt(end, oursChanges[0].end)
+				oursChanges = oursChanges[1:]
+				continue
+			}
+			if len(theirsChanges) > 0 && theirsChanges[0].start <= end {
+				theirsGroup = append(theirsGroup, theirsChanges[0])
+				end = maxInt(end, theirsChanges[0].end)
+				theirsChanges = theirsChanges[1:]
+				continue
+			}
+			break
+		}
+
+		result = append(result, base[line:start]...)
+		oursLines := applyChanges(base, start, end, oursGroup)
+		theirsLines := applyChanges(base, start, end, theirsGroup)
+		switch {
+		case len(theirsGroup) == 0:
+			result = append(result, oursLines...)
+		case len(oursGroup) == 0 || equalLines(oursLines, theirsLines):
+			result = append(result, theirsLines...)
+		default:
+			conflicts++
+			result = append(result, "<<<<<<< "+oursName)
+			result = append(result, oursLines...)
+			result = append(result, "=======")
+			result = append(result, theirsLines...)
+			result = append(result, ">>>>>>> "+theirsName)
+		}
+		line = end
+	}
+	return append(result, base[line:]...), conflicts
+}
+
+// maxInt returns the larger of a and b.
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// equalLines reports whether both lists contain the same lines.
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// MergeText is like Merge3 but operates on texts instead of lines. The merged text ends with a newline if ours or
+// theirs does.
+func MergeText(base, ours, theirs, oursName, theirsName string) (string, int) {
+	lines, conflicts := Merge3(SplitLines(base), SplitLines(ours), SplitLines(theirs), oursName, theirsName)
+	merged := strings.Join(lines, "\n")
+	if len(lines) > 0 && (strings.HasSuffix(ours, "\n") || strings.HasSuffix(theirs, "\n")) {
+		merged += "\n"
+	}
+	return merged, conflicts
+}
@@ -0,0 +1,122 @@
+// Package expr implements the small expression language that is used for conditions in package configs; e.g.
+// when = "use_docker && license != 'none'".
+//
+// Supported are the literals true, false, numbers and single or double quoted strings, variable identifiers,
+// the comparison operators ==, !=, <, <=, >, >=, the logical operators &&, || and ! as well as parentheses.
+// Values are truthy if they are true, a non-empty string or a non-zero number.
+package expr
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// UndefinedVariableError represents an error for the case that an expression references a variable which is not
+// defined.
+type UndefinedVariableError struct {
+	Name string
+}
+
+func (e *UndefinedVariableError) Error() string {
+	return fmt.Sprintf("undefined variable '%s'", e.Name)
+}
+
+// Expression is a parsed expression that can be evaluated multiple times.
+type Expression struct {
+	source string
+	root   node
+}
+
+// Parse parses the given expression.
+func Parse(source string) (*Expression, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.atEnd() {
+		return nil, fmt.Errorf("unexpected '%s' at position %d", p.peek().text, p.peek().pos)
+	}
+	return &Expression{source: source, root: root}, nil
+}
+
+// Eval parses and evaluates the given expression against the given variables and reports whether the result is
+// truthy.
+func Eval(source string, vars map[string]interface{}) (bool, error) {
+	e, err := Parse(source)
+	if err != nil {
+		return false, err
+	}
+	return e.Eval(vars)
+}
+
+// Eval evaluates the expression against the given variables and reports whether the result is truthy.
+func (e *Expression) Eval(vars map[string]interface{}) (bool, error) {
+	value, err := e.root.eval(vars)
+	if err != nil {
+		return false, err
+	}
+	return truthy(value), nil
+}
+
+// String returns the source of the expression.
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Identifiers returns the names of all variables that are referenced by the expression.
+func (e *Expression) Identifiers() []string {
+	names := make([]string, 0)
+	seen := make(map[string]bool)
+	e.root.walk(func(n node) {
+		if id, ok := n.(identifierNode); ok && !seen[string(id)] {
+			seen[string(id)] = true
+			names = append(names, string(id))
+		}
+	})
+	return names
+}
+
+// truthy reports whether a value counts as true.
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case float64:
+		return v != 0
+	default:
+		return false
+	}
+}
+
+// normalize converts a variable value to one of the types the evaluator works with: bool, string and float64.
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case bool, string, float64:
+		return v
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case nil:
+		return false
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// format returns the string representation of a normalized value.
+func format(value interface{}) string {
+	if f, ok := value.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
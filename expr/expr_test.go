@@ -0,0 +1,59 @@
+package expr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEval(t *testing.T) {
+	vars := map[string]interface{}{
+		"use_docker": true,
+		"use_ci":     false,
+		"license":    "MIT",
+		"port":       8080,
+		"name":       "",
+	}
+
+	tests := []struct {
+		name    string
+		expr    string
+		want    bool
+		wantErr bool
+	}{
+		{name: "Bool variable", expr: "use_docker", want: true},
+		{name: "Negation", expr: "!use_ci", want: true},
+		{name: "And", expr: "use_docker && license != 'none'", want: true},
+		{name: "And false", expr: "use_docker && use_ci", want: false},
+		{name: "Or", expr: "use_ci || license == \"MIT\"", want: true},
+		{name: "Parentheses", expr: "!(use_ci || use_docker)", want: false},
+		{name: "Number comparison", expr: "port >= 1024 && port < 65536", want: true},
+		{name: "Number equals string", expr: "port == '8080'", want: true},
+		{name: "Empty string is falsy", expr: "name", want: false},
+		{name: "Literal", expr: "true", want: true},
+		{name: "Short-circuit skips undefined", expr: "use_ci && undefined", want: false},
+		{name: "Undefined variable", expr: "undefined", wantErr: true},
+		{name: "Invalid ordering", expr: "license < 3", wantErr: true},
+		{name: "Missing operand", expr: "use_docker &&", wantErr: true},
+		{name: "Unterminated string", expr: "license == 'MIT", wantErr: true},
+		{name: "Missing parenthesis", expr: "(use_docker", wantErr: true},
+		{name: "Trailing token", expr: "use_docker use_ci", wantErr: true},
+		{name: "Unknown character", expr: "use_docker & use_ci", wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := Eval(test.expr, vars)
+		if test.wantErr {
+			assert.Error(t, err, test.name)
+			continue
+		}
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.want, got, test.name)
+	}
+}
+
+func TestIdentifiers(t *testing.T) {
+	e, err := Parse("use_docker && (license != 'none' || !use_docker)")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"use_docker", "license"}, e.Identifiers())
+}
@@ -0,0 +1,259 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// node is a node of the syntax tree of an expression.
+type node interface {
+	eval(vars map[string]interface{}) (interface{}, error)
+	walk(fn func(node))
+}
+
+type literalNode struct{ value interface{} }
+
+type identifierNode string
+
+type notNode struct{ operand node }
+
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) atEnd() bool { return p.pos >= len(p.tokens) }
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+// acceptOperator consumes the next token if it is one of the given operators.
+func (p *parser) acceptOperator(ops ...string) (string, bool) {
+	if p.atEnd() || p.peek().kind != tokenOperator {
+		return "", false
+	}
+	for _, op := range ops {
+		if p.peek().text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOperator("||")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOperator("&&")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseNot() (node, error) {
+	if _, ok := p.acceptOperator("!"); ok {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.acceptOperator("==", "!=", "<=", ">=", "<", ">")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	return &binaryNode{op: op, left: left, right: right}, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	if p.atEnd() {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	t := p.peek()
+	p.pos++
+
+	switch t.kind {
+	case tokenLeftParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.atEnd() || p.peek().kind != tokenRightParen {
+			return nil, fmt.Errorf("missing ')' for '(' at position %d", t.pos)
+		}
+		p.pos++
+		return inner, nil
+	case tokenString:
+		return &literalNode{value: t.text}, nil
+	case tokenNumber:
+		number, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s' at position %d", t.text, t.pos)
+		}
+		return &literalNode{value: number}, nil
+	case tokenIdentifier:
+		switch t.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		}
+		return identifierNode(t.text), nil
+	default:
+		return nil, fmt.Errorf("unexpected '%s' at position %d", t.text, t.pos)
+	}
+}
+
+func (n *literalNode) eval(map[string]interface{}) (interface{}, error) { return n.value, nil }
+
+func (n *literalNode) walk(fn func(node)) { fn(n) }
+
+func (n identifierNode) eval(vars map[string]interface{}) (interface{}, error) {
+	value, ok := vars[string(n)]
+	if !ok {
+		return nil, &UndefinedVariableError{Name: string(n)}
+	}
+	return normalize(value), nil
+}
+
+func (n identifierNode) walk(fn func(node)) { fn(n) }
+
+func (n *notNode) eval(vars map[string]interface{}) (interface{}, error) {
+	value, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	return !truthy(value), nil
+}
+
+func (n *notNode) walk(fn func(node)) {
+	fn(n)
+	n.operand.walk(fn)
+}
+
+func (n *binaryNode) eval(vars map[string]interface{}) (interface{}, error) {
+	left, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	// Short-circuit logical operators
+	switch n.op {
+	case "&&":
+		if !truthy(left) {
+			return false, nil
+		}
+		right, err := n.right.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		return truthy(right), nil
+	case "||":
+		if truthy(left) {
+			return true, nil
+		}
+		right, err := n.right.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		return truthy(right), nil
+	}
+
+	right, err := n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	return compare(n.op, left, right)
+}
+
+func (n *binaryNode) walk(fn func(node)) {
+	fn(n)
+	n.left.walk(fn)
+	n.right.walk(fn)
+}
+
+// compare applies a comparison operator to two values. Values of different types are compared by their string
+// representation for equality; ordering requires two numbers or two strings.
+func compare(op string, left, right interface{}) (bool, error) {
+	switch op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	}
+
+	switch l := left.(type) {
+	case float64:
+		if r, ok := right.(float64); ok {
+			return order(op, l < r, l == r), nil
+		}
+	case string:
+		if r, ok := right.(string); ok {
+			return order(op, l < r, l == r), nil
+		}
+	}
+	return false, fmt.Errorf("cannot compare %s %s %s", format(left), op, format(right))
+}
+
+func equal(left, right interface{}) bool {
+	if left == right {
+		return true
+	}
+	return format(left) == format(right)
+}
+
+func order(op string, less, equal bool) bool {
+	switch op {
+	case "<":
+		return less
+	case "<=":
+		return less || equal
+	case ">":
+		return !less && !equal
+	default:
+		return !less
+	}
+}
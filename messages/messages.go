@@ -78,7 +78,8 @@ func Errorf(format string, err error, args ...interface{}) {
 }
 
 func formatErrorMessage(format string, err error) string {
-	errorString := colorError("error") + "=" + err.Error()
+	// The error is part of the format; escape it so that it is printed as is.
+	errorString := colorError("error") + "=" + strings.ReplaceAll(err.Error(), "%", "%%")
 	format = strings.TrimSpace(format)
 	if len(format) < 1 {
 		return errorString
@@ -0,0 +1,179 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/nikoksr/proji/messages"
+)
+
+// Types of plugins.
+const (
+	TypeLua   = "lua"   // Lua script run by the embedded interpreter.
+	TypeShell = "shell" // Shell script run by sh.
+	TypeExec  = "exec"  // Any executable file.
+)
+
+// Types returns the list of all plugin types.
+func Types() []string {
+	return []string{TypeLua, TypeShell, TypeExec}
+}
+
+// IsType reports whether name is a known plugin type.
+func IsType(name string) bool {
+	for _, pluginType := range Types() {
+		if pluginType == name {
+			return true
+		}
+	}
+	return false
+}
+
+// TypeOf infers the plugin type from the file extension of the plugin path. Files that are neither lua nor shell
+// scripts are treated as executables.
+func TypeOf(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".lua":
+		return TypeLua
+	case ".sh", ".bash":
+		return TypeShell
+	default:
+		return TypeExec
+	}
+}
+
+// RunShell executes the shell script at the given path with sh. See RunExec for the environment the script runs in.
+func RunShell(ctx context.Context, path string, env *Env) error {
+	return runCommand(ctx, path, "sh", append([]string{path}, env.Args...), env)
+}
+
+// RunExec executes the executable at the given path. The executable is run inside of the project folder with the
+// plugin arguments and without input. Lines written to stdout are printed as info messages, lines written to stderr as
+// warnings. The executable receives the following environment variables:
+//
+//	PROJI_API_VERSION      Version of the plugin API. Incremented on breaking changes.
+//	PROJI_PROJECT_NAME     Name of the project.
+//	PROJI_PROJECT_PATH     Absolute path of the project.
+//	PROJI_PACKAGE_NAME     Name of the package the project is based on.
+//	PROJI_PACKAGE_LABEL    Label of the package the project is based on.
+//	PROJI_TEMPLATES_PATH   Path of proji's templates folder.
+//	PROJI_OPTIONS          JSON object of the plugin options.
+//	PROJI_VAR_<NAME>       Value of the package variable with the upper-cased name NAME.
+//
+// Besides these, the executable inherits proji's environment. In sandbox mode the executable requires the exec
+// capability and only inherits the environment if the env capability was granted; otherwise it only receives PATH.
+func RunExec(ctx context.Context, path string, env *Env) error {
+	return runCommand(ctx, path, path, env.Args, env)
+}
+
+// runCommand runs a command for the plugin at the given path and streams its output through messages.
+func runCommand(ctx context.Context, path, name string, args []string, env *Env) error {
+	if !env.allows(CapabilityExec) {
+		return &CapabilityError{Capability: CapabilityExec}
+	}
+	environment, err := env.Environment()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := env.runContext(ctx)
+	defer cancel()
+
+	stdout := &lineWriter{emit: func(line string) { messages.Infof("%s", line) }}
+	stderr := &lineWriter{emit: func(line string) { messages.Warningf("%s", line) }}
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = env.Data.Path
+	cmd.Env = environment
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err = cmd.Run()
+	stdout.Flush()
+	stderr.Flush()
+	if err != nil {
+		err = fmt.Errorf("plugin %s failed, %s", path, err.Error())
+	}
+	return env.checkAborted(ctx, path, err)
+}
+
+// Environment returns the environment that shell and executable plugins run with. See RunExec for a description of the
+// variables.
+func (e *Env) Environment() ([]string, error) {
+	var environment []string
+	if e.allows(CapabilityEnv) {
+		environment = os.Environ()
+	} else {
+		environment = []string{"PATH=" + os.Getenv("PATH")}
+	}
+
+	options := e.Options
+	if options == nil {
+		options = map[string]interface{}{}
+	}
+	encodedOptions, err := json.Marshal(options)
+	if err != nil {
+		return nil, err
+	}
+	environment = append(environment,
+		fmt.Sprintf("PROJI_API_VERSION=%d", apiVersion),
+		"PROJI_PROJECT_NAME="+e.Data.Name,
+		"PROJI_PROJECT_PATH="+e.Data.Path,
+		"PROJI_TEMPLATES_PATH="+e.TemplatesPath,
+		"PROJI_OPTIONS="+string(encodedOptions),
+	)
+	if e.Data.Package != nil {
+		environment = append(environment,
+			"PROJI_PACKAGE_NAME="+e.Data.Package.Name,
+			"PROJI_PACKAGE_LABEL="+e.Data.Package.Label,
+		)
+	}
+
+	names := make([]string, 0, len(e.Data.Vars))
+	for name := range e.Data.Vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		environment = append(environment, fmt.Sprintf("PROJI_VAR_%s=%v", strings.ToUpper(name), e.Data.Vars[name]))
+	}
+	return environment, nil
+}
+
+// lineWriter is an io.Writer that calls emit for every complete line written to it.
+type lineWriter struct {
+	mu   sync.Mutex
+	buf  bytes.Buffer
+	emit func(line string)
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf.Write(p)
+	for {
+		i := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		line := string(w.buf.Next(i + 1))
+		w.emit(strings.TrimRight(line, "\r\n"))
+	}
+}
+
+// Flush emits the remaining output that is not terminated by a newline.
+func (w *lineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.buf.Len() > 0 {
+		w.emit(w.buf.String())
+		w.buf.Reset()
+	}
+}
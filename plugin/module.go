@@ -0,0 +1,219 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/nikoksr/proji/messages"
+	"github.com/nikoksr/proji/render"
+	lua "github.com/yuin/gopher-lua"
+)
+
+const (
+	moduleName = "proji"
+	apiVersion = 1
+)
+
+// module holds the state that the functions of the proji lua module operate on.
+type module struct {
+	env *Env
+}
+
+// newModuleLoader returns a loader function for the proji lua module bound to the given environment.
+func newModuleLoader(env *Env) lua.LGFunction {
+	return func(L *lua.LState) int {
+		m := &module{env: env}
+		table := L.NewTable()
+		L.SetFuncs(table, map[string]lua.LGFunction{
+			"path":        m.path,
+			"read_file":   m.readFile,
+			"write_file":  m.writeFile,
+			"render":      m.render,
+			"render_file": m.renderFile,
+			"run":         m.run,
+			"getenv":      m.getenv,
+		})
+		table.RawSetString("log", L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+			"info":    logInfo,
+			"success": logSuccess,
+			"warning": logWarning,
+			"error":   logError,
+		}))
+		table.RawSetString("api_version", lua.LNumber(apiVersion))
+		table.RawSetString("project", m.projectTable(L))
+		table.RawSetString("package", m.packageTable(L))
+		table.RawSetString("vars", varsToTable(L, env.Data.Vars))
+		table.RawSetString("args", stringsToTable(L, env.Args))
+		table.RawSetString("options", mapToTable(L, env.Options))
+		L.Push(table)
+		return 1
+	}
+}
+
+func (m *module) projectTable(L *lua.LState) *lua.LTable {
+	table := L.NewTable()
+	table.RawSetString("name", lua.LString(m.env.Data.Name))
+	table.RawSetString("path", lua.LString(m.env.Data.Path))
+	return table
+}
+
+func (m *module) packageTable(L *lua.LState) *lua.LTable {
+	table := L.NewTable()
+	if m.env.Data.Package != nil {
+		table.RawSetString("name", lua.LString(m.env.Data.Package.Name))
+		table.RawSetString("label", lua.LString(m.env.Data.Package.Label))
+	}
+	return table
+}
+
+// resolve returns the absolute path of a path inside of the project folder.
+func (m *module) resolve(path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(m.env.Data.Path, path)
+}
+
+// pushError pushes nil and an error message onto the stack. Follows the lua convention for functions that can fail.
+func pushError(L *lua.LState, err error) int {
+	L.Push(lua.LNil)
+	L.Push(lua.LString(err.Error()))
+	return 2
+}
+
+// require raises a lua error if the plugin was not granted the given capability.
+func (m *module) require(L *lua.LState, capability string) {
+	if !m.env.allows(capability) {
+		L.RaiseError("%s", (&CapabilityError{Capability: capability}).Error())
+	}
+}
+
+// resolveFile returns the absolute path of a file that the plugin wants to access. It raises a lua error if the plugin
+// has no filesystem access or the path lies outside of the project folder in sandbox mode.
+func (m *module) resolveFile(L *lua.LState, path string) string {
+	m.require(L, CapabilityFilesystem)
+	path = m.resolve(path)
+	err := m.env.checkPath(path)
+	if err != nil {
+		L.RaiseError("%s", err.Error())
+	}
+	return path
+}
+
+func (m *module) path(L *lua.LState) int {
+	L.Push(lua.LString(m.resolve(L.CheckString(1))))
+	return 1
+}
+
+func (m *module) readFile(L *lua.LState) int {
+	content, err := ioutil.ReadFile(m.resolveFile(L, L.CheckString(1)))
+	if err != nil {
+		return pushError(L, err)
+	}
+	L.Push(lua.LString(content))
+	return 1
+}
+
+func (m *module) writeFile(L *lua.LState) int {
+	path := m.resolveFile(L, L.CheckString(1))
+	content := L.CheckString(2)
+
+	err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err == nil {
+		err = ioutil.WriteFile(path, []byte(content), 0666)
+	}
+	if err != nil {
+		return pushError(L, err)
+	}
+	L.Push(lua.LTrue)
+	return 1
+}
+
+func (m *module) render(L *lua.LState) int {
+	rendered, err := render.Text("plugin", L.CheckString(1), m.env.Data)
+	if err != nil {
+		return pushError(L, err)
+	}
+	L.Push(lua.LString(rendered))
+	return 1
+}
+
+func (m *module) renderFile(L *lua.LState) int {
+	name := L.CheckString(1)
+	dst := m.resolveFile(L, L.CheckString(2))
+	src := filepath.Join(m.env.TemplatesPath, name)
+	if m.env.Sandboxed && !isSubPath(m.env.TemplatesPath, src) {
+		L.RaiseError("%s", (&CapabilityError{
+			Capability: CapabilityFilesystem,
+			Reason:     fmt.Sprintf("template %s is outside of the templates folder", name),
+		}).Error())
+	}
+
+	err := render.File(src, dst, m.env.Data)
+	if err != nil {
+		return pushError(L, err)
+	}
+	L.Push(lua.LTrue)
+	return 1
+}
+
+func (m *module) run(L *lua.LState) int {
+	m.require(L, CapabilityExec)
+	name := L.CheckString(1)
+	args := make([]string, 0, L.GetTop()-1)
+	for i := 2; i <= L.GetTop(); i++ {
+		args = append(args, L.CheckString(i))
+	}
+
+	environment, err := m.env.Environment()
+	if err != nil {
+		return pushError(L, err)
+	}
+
+	cmd := exec.CommandContext(L.Context(), name, args...)
+	cmd.Dir = m.env.Data.Path
+	cmd.Env = environment
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error() + "\n" + string(output)))
+		return 2
+	}
+	L.Push(lua.LString(output))
+	return 1
+}
+
+func (m *module) getenv(L *lua.LState) int {
+	m.require(L, CapabilityEnv)
+	value, ok := os.LookupEnv(L.CheckString(1))
+	if !ok {
+		L.Push(lua.LNil)
+		return 1
+	}
+	L.Push(lua.LString(value))
+	return 1
+}
+
+func logInfo(L *lua.LState) int {
+	messages.Infof("%s", L.CheckString(1))
+	return 0
+}
+
+func logSuccess(L *lua.LState) int {
+	messages.Successf("%s", L.CheckString(1))
+	return 0
+}
+
+func logWarning(L *lua.LState) int {
+	messages.Warningf("%s", L.CheckString(1))
+	return 0
+}
+
+func logError(L *lua.LState) int {
+	messages.Errorf("", errors.New(L.CheckString(1)))
+	return 0
+}
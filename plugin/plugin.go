@@ -0,0 +1,195 @@
+// Package plugin implements the execution of proji plugins.
+//
+// There are three types of plugins. Lua plugins are executed by an embedded lua interpreter, shell plugins are run by
+// sh and executable plugins are run directly. Shell and executable plugins are described further in RunExec.
+//
+// Lua plugins have access to the preloaded module proji which exposes information about the project that is being
+// created and a set of helper functions. The module is loaded with:
+//
+//	local proji = require("proji")
+//
+// # Fields
+//
+//	proji.api_version      Version of the module API. Incremented on breaking changes.
+//	proji.project.name     Name of the project.
+//	proji.project.path     Absolute path of the project.
+//	proji.package.name     Name of the package the project is based on.
+//	proji.package.label    Label of the package the project is based on.
+//	proji.vars             Table of the package variable values, keyed by variable name.
+//	proji.args             List of the plugin arguments as defined in the package config.
+//	proji.options          Table of the plugin options as defined in the package config.
+//
+// # Functions
+//
+// Relative paths are always resolved relative to the project folder. This applies to the file functions of the standard
+// libraries as well; e.g. io.open and os.remove. Commands started with os.execute and io.popen run inside of the
+// project folder. Functions that can fail return nil and an error message on failure.
+//
+//	proji.path(path)                    Returns the absolute path of a path inside of the project.
+//	proji.read_file(path)               Returns the content of a file.
+//	proji.write_file(path, content)     Writes content to a file. Missing parent folders are created.
+//	proji.render(text)                  Renders template text and returns the result.
+//	proji.render_file(template, path)   Renders a file of proji's templates folder to path.
+//	proji.run(command, ...)             Runs a command with the given arguments inside of the project folder and
+//	                                    returns its combined output. The command gets the environment of shell
+//	                                    and executable plugins; see RunExec.
+//	proji.getenv(name)                  Returns the value of an environment variable or nil if it is not set.
+//	proji.log.info(message)             Prints an info message.
+//	proji.log.success(message)          Prints a success message.
+//	proji.log.warning(message)          Prints a warning message.
+//	proji.log.error(message)            Prints an error message.
+//
+// # Sandbox
+//
+// Plugins of untrusted packages run in sandbox mode. Only the base, package, table, string, math and coroutine
+// libraries and the time functions of the os library are available; dofile, loadfile and requiring modules from the
+// filesystem are disabled. Access to the host system is only possible through the proji module and requires the
+// package to declare the respective capability:
+//
+//	fs      proji.read_file, proji.write_file and proji.render_file; limited to paths inside of the project folder.
+//	exec    proji.run; shell and executable plugins can't run at all without it.
+//	env     proji.getenv
+//
+// # Cancellation
+//
+// Plugins run with a timeout and are aborted when the context they were started with is cancelled, e.g. because the
+// user pressed Ctrl-C. Commands started with proji.run are killed in that case as well.
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nikoksr/proji/render"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Env describes the project that a plugin is executed for.
+type Env struct {
+	Data          *render.Data           // Project related values; also used to render templates.
+	TemplatesPath string                 // Path of proji's templates folder.
+	PluginsPath   string                 // Path of proji's plugins folder.
+	Args          []string               // Arguments of the plugin.
+	Options       map[string]interface{} // Options of the plugin.
+	Sandboxed     bool                   // Whether the plugin runs in sandbox mode.
+	Capabilities  []string               // Capabilities granted to the plugin in sandbox mode.
+	Timeout       time.Duration          // Maximum run time of the plugin; zero disables the timeout.
+}
+
+// AbortedError is returned when a plugin was aborted before it finished because it timed out or was cancelled.
+type AbortedError struct {
+	Path    string
+	Timeout time.Duration
+	Err     error
+}
+
+func (e *AbortedError) Error() string {
+	if errors.Is(e.Err, context.DeadlineExceeded) {
+		return fmt.Sprintf("plugin %s was aborted, timed out after %s", e.Path, e.Timeout)
+	}
+	return fmt.Sprintf("plugin %s was aborted, %s", e.Path, e.Err.Error())
+}
+
+// Unwrap returns the reason the plugin was aborted; either context.DeadlineExceeded or context.Canceled.
+func (e *AbortedError) Unwrap() error {
+	return e.Err
+}
+
+// Run executes the plugin at the given path with the runner of the given plugin type.
+func Run(ctx context.Context, pluginType, path string, env *Env) error {
+	switch pluginType {
+	case TypeLua:
+		return RunLua(ctx, path, env)
+	case TypeShell:
+		return RunShell(ctx, path, env)
+	case TypeExec:
+		return RunExec(ctx, path, env)
+	default:
+		return fmt.Errorf("unknown plugin type '%s'", pluginType)
+	}
+}
+
+// RunLua executes the lua plugin at the given path. If the environment is sandboxed, the plugin runs with a restricted
+// set of libraries and can only use the capabilities that were granted to it. The plugin is aborted when ctx is
+// cancelled or its timeout expires.
+func RunLua(ctx context.Context, path string, env *Env) error {
+	ctx, cancel := env.runContext(ctx)
+	defer cancel()
+
+	L := newState(env)
+	defer L.Close()
+	L.SetContext(ctx)
+	L.PreloadModule(moduleName, newModuleLoader(env))
+	return env.checkAborted(ctx, path, L.DoFile(path))
+}
+
+// runContext returns the context that a plugin runs with. It expires once the timeout of the plugin is reached.
+func (e *Env) runContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if e.Timeout > 0 {
+		return context.WithTimeout(ctx, e.Timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// checkAborted replaces the error of a plugin run with an AbortedError if the plugin failed because its context expired.
+func (e *Env) checkAborted(ctx context.Context, path string, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return &AbortedError{Path: path, Timeout: e.Timeout, Err: ctx.Err()}
+	}
+	return err
+}
+
+// varsToTable converts variable values to a lua table.
+func varsToTable(L *lua.LState, vars render.Vars) *lua.LTable {
+	return mapToTable(L, vars)
+}
+
+// mapToTable converts a map to a lua table.
+func mapToTable(L *lua.LState, m map[string]interface{}) *lua.LTable {
+	table := L.NewTable()
+	for key, value := range m {
+		table.RawSetString(key, toLuaValue(L, value))
+	}
+	return table
+}
+
+// stringsToTable converts a list of strings to a lua array.
+func stringsToTable(L *lua.LState, list []string) *lua.LTable {
+	table := L.NewTable()
+	for _, item := range list {
+		table.Append(lua.LString(item))
+	}
+	return table
+}
+
+// toLuaValue converts a go value to a lua value. Lists and maps are converted recursively.
+func toLuaValue(L *lua.LState, value interface{}) lua.LValue {
+	switch v := value.(type) {
+	case nil:
+		return lua.LNil
+	case bool:
+		return lua.LBool(v)
+	case int:
+		return lua.LNumber(v)
+	case int64:
+		return lua.LNumber(v)
+	case float64:
+		return lua.LNumber(v)
+	case string:
+		return lua.LString(v)
+	case []string:
+		return stringsToTable(L, v)
+	case []interface{}:
+		table := L.NewTable()
+		for _, item := range v {
+			table.Append(toLuaValue(L, item))
+		}
+		return table
+	case map[string]interface{}:
+		return mapToTable(L, v)
+	default:
+		return lua.LString(fmt.Sprint(v))
+	}
+}
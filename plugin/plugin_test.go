@@ -0,0 +1,267 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nikoksr/proji/render"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunLua(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "proji-plugin")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	script := filepath.Join(tmpDir, "plugin.lua")
+	projectPath := filepath.Join(tmpDir, "my-project")
+	assert.NoError(t, ioutil.WriteFile(script, []byte(`
+local proji = require("proji")
+assert(proji.api_version == 1)
+assert(proji.project.path == proji.path("."))
+local text = proji.render("{{ .Name }} ({{ .Package.Label }}) {{ .Vars.license }}")
+local ok, err = proji.write_file("docs/info.txt", text .. " " .. tostring(proji.vars.use_docker))
+assert(ok, err)
+local content = proji.read_file("docs/info.txt")
+assert(content == "my-project (py) MIT true", content)
+local missing, readErr = proji.read_file("missing.txt")
+assert(missing == nil and readErr ~= nil)
+assert(proji.args[1] == "--bare" and #proji.args == 1)
+assert(proji.options.branch == "main" and proji.options.depth == 2)
+assert(proji.options.remotes[2] == "upstream")
+`), 0644))
+
+	env := &Env{
+		Data: render.NewData("my-project", projectPath, "python", "py", render.Vars{
+			"license":    "MIT",
+			"use_docker": true,
+		}),
+		Args: []string{"--bare"},
+		Options: map[string]interface{}{
+			"branch":  "main",
+			"depth":   int64(2),
+			"remotes": []interface{}{"origin", "upstream"},
+		},
+	}
+	assert.NoError(t, RunLua(context.Background(), script, env))
+	assert.FileExists(t, filepath.Join(projectPath, "docs", "info.txt"))
+}
+
+func TestRunLuaLog(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "proji-plugin")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	script := filepath.Join(tmpDir, "plugin.lua")
+	assert.NoError(t, ioutil.WriteFile(script, []byte(`
+local proji = require("proji")
+proji.log.info("cloning %s")
+proji.log.error("clone of " .. proji.project.name .. " failed at 50%")
+`), 0644))
+
+	// Capture the output of the messages package
+	stdout, stderr := os.Stdout, os.Stderr
+	outReader, outWriter, err := os.Pipe()
+	assert.NoError(t, err)
+	errReader, errWriter, err := os.Pipe()
+	assert.NoError(t, err)
+	os.Stdout, os.Stderr = outWriter, errWriter
+	env := &Env{Data: render.NewData("my-project", filepath.Join(tmpDir, "my-project"), "python", "py", nil)}
+	err = RunLua(context.Background(), script, env)
+	os.Stdout, os.Stderr = stdout, stderr
+	assert.NoError(t, err)
+
+	assert.NoError(t, outWriter.Close())
+	assert.NoError(t, errWriter.Close())
+	info, err := ioutil.ReadAll(outReader)
+	assert.NoError(t, err)
+	assert.Contains(t, string(info), "cloning %s\n")
+	errorOutput, err := ioutil.ReadAll(errReader)
+	assert.NoError(t, err)
+	assert.Contains(t, string(errorOutput), "=clone of my-project failed at 50%\n")
+	assert.NotContains(t, string(errorOutput), "plugin error")
+}
+
+func TestRunLuaWorkingDir(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "proji-plugin")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	script := filepath.Join(tmpDir, "plugin.lua")
+	projectPath := filepath.Join(tmpDir, "my-project")
+	assert.NoError(t, os.MkdirAll(projectPath, os.ModePerm))
+	assert.NoError(t, ioutil.WriteFile(script, []byte(`
+local file = assert(io.open("a.txt", "w"))
+file:write("hello")
+file:close()
+assert(os.rename("a.txt", "b.txt"))
+for line in io.lines("b.txt") do
+	assert(line == "hello", line)
+end
+assert(os.execute("test -f b.txt") == 0)
+local output = io.popen("cat b.txt"):read("*a")
+assert(output == "hello", output)
+`), 0644))
+
+	L := newState(&Env{Data: render.NewData("my-project", projectPath, "", "", nil)})
+	defer L.Close()
+	assert.NoError(t, L.DoFile(script))
+	assert.FileExists(t, filepath.Join(projectPath, "b.txt"))
+	assert.NoFileExists(t, "b.txt")
+}
+
+func TestRunLuaSandboxed(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "proji-plugin")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	projectPath := filepath.Join(tmpDir, "my-project")
+	assert.NoError(t, os.MkdirAll(projectPath, os.ModePerm))
+	assert.NoError(t, os.Symlink(tmpDir, filepath.Join(projectPath, "escape")))
+
+	tests := []struct {
+		name         string
+		script       string
+		capabilities []string
+		wantErr      bool
+	}{
+		{name: "Safe libraries", script: `assert(string.upper("a") == "A" and math.max(1, 2) == 2 and os.time() > 0)`},
+		{name: "No io library", script: `assert(io == nil)`},
+		{name: "No os.execute", script: `assert(os.execute == nil and os.remove == nil)`},
+		{name: "No os.execute via require", script: `assert(require("os").execute == nil)`},
+		{name: "No dofile", script: `assert(dofile == nil and loadfile == nil)`},
+		{name: "Write without capability", script: `require("proji").write_file("a.txt", "a")`, wantErr: true},
+		{
+			name:         "Write with capability",
+			script:       `assert(require("proji").write_file("a.txt", "a"))`,
+			capabilities: []string{CapabilityFilesystem},
+		},
+		{
+			name:         "Write outside of project",
+			script:       `require("proji").write_file("../a.txt", "a")`,
+			capabilities: []string{CapabilityFilesystem},
+			wantErr:      true,
+		},
+		{
+			name:         "Write through symlink",
+			script:       `require("proji").write_file("escape/a.txt", "a")`,
+			capabilities: []string{CapabilityFilesystem},
+			wantErr:      true,
+		},
+		{name: "Run without capability", script: `require("proji").run("true")`, wantErr: true},
+		{
+			name:         "Run without env capability",
+			script:       `assert(require("proji").run("sh", "-c", "printf %s \"$PROJI_SANDBOX_TEST\"") == "")`,
+			capabilities: []string{CapabilityExec},
+		},
+		{
+			name:         "Run with env capability",
+			script:       `assert(require("proji").run("sh", "-c", "printf %s \"$PROJI_SANDBOX_TEST\"") == "1")`,
+			capabilities: []string{CapabilityExec, CapabilityEnv},
+		},
+		{name: "Getenv without capability", script: `require("proji").getenv("HOME")`, wantErr: true},
+		{
+			name:         "Getenv with capability",
+			script:       `assert(require("proji").getenv("PROJI_SANDBOX_TEST") == "1")`,
+			capabilities: []string{CapabilityEnv},
+		},
+	}
+
+	assert.NoError(t, os.Setenv("PROJI_SANDBOX_TEST", "1"))
+	defer os.Unsetenv("PROJI_SANDBOX_TEST")
+
+	script := filepath.Join(tmpDir, "plugin.lua")
+	for _, test := range tests {
+		assert.NoError(t, ioutil.WriteFile(script, []byte(test.script), 0644), test.name)
+		env := &Env{
+			Data:         render.NewData("my-project", projectPath, "python", "py", nil),
+			Sandboxed:    true,
+			Capabilities: test.capabilities,
+		}
+		err := RunLua(context.Background(), script, env)
+		if test.wantErr {
+			assert.Error(t, err, test.name)
+			continue
+		}
+		assert.NoError(t, err, test.name)
+	}
+	assert.NoFileExists(t, filepath.Join(tmpDir, "a.txt"))
+}
+
+func TestRunLuaAborted(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "proji-plugin")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	script := filepath.Join(tmpDir, "plugin.lua")
+	assert.NoError(t, ioutil.WriteFile(script, []byte(`while true do end`), 0644))
+	env := &Env{Data: render.NewData("my-project", tmpDir, "python", "py", nil)}
+
+	// Timeout
+	env.Timeout = 50 * time.Millisecond
+	err = RunLua(context.Background(), script, env)
+	aborted, ok := err.(*AbortedError)
+	assert.True(t, ok, "expected an AbortedError, got %v", err)
+	if ok {
+		assert.Equal(t, script, aborted.Path)
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	}
+
+	// Cancellation
+	env.Timeout = 0
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	err = RunLua(ctx, script, env)
+	assert.True(t, errors.Is(err, context.Canceled), "expected cancellation, got %v", err)
+}
+
+func TestRunShell(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "proji-plugin")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	projectPath := filepath.Join(tmpDir, "my-project")
+	assert.NoError(t, os.MkdirAll(projectPath, os.ModePerm))
+	script := filepath.Join(tmpDir, "plugin.sh")
+	assert.NoError(t, ioutil.WriteFile(script, []byte(`
+echo "$PROJI_PROJECT_NAME $PROJI_PACKAGE_LABEL $PROJI_VAR_LICENSE $PROJI_OPTIONS $1 $(pwd)" > info.txt
+`), 0644))
+
+	env := &Env{
+		Data:    render.NewData("my-project", projectPath, "python", "py", render.Vars{"license": "MIT"}),
+		Args:    []string{"--bare"},
+		Options: map[string]interface{}{"depth": 2},
+	}
+	assert.Equal(t, TypeShell, TypeOf(script))
+	assert.NoError(t, Run(context.Background(), TypeShell, script, env))
+
+	content, err := ioutil.ReadFile(filepath.Join(projectPath, "info.txt"))
+	assert.NoError(t, err)
+	wd, err := filepath.EvalSymlinks(projectPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "my-project py MIT {\"depth\":2} --bare "+wd+"\n", string(content))
+
+	// Failing script
+	assert.NoError(t, ioutil.WriteFile(script, []byte(`exit 3`), 0644))
+	assert.Error(t, Run(context.Background(), TypeShell, script, env))
+
+	// Sandboxed without the exec capability
+	env.Sandboxed = true
+	err = Run(context.Background(), TypeShell, script, env)
+	_, ok := err.(*CapabilityError)
+	assert.True(t, ok, "expected a CapabilityError, got %v", err)
+}
+
+func TestLineWriter(t *testing.T) {
+	var lines []string
+	w := &lineWriter{emit: func(line string) { lines = append(lines, line) }}
+	_, _ = w.Write([]byte("first\nsec"))
+	_, _ = w.Write([]byte("ond\r\nthird"))
+	w.Flush()
+	assert.Equal(t, []string{"first", "second", "third"}, lines)
+}
@@ -0,0 +1,162 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Capabilities that a package has to declare for its plugins to be granted access to the respective resources when
+// running in sandbox mode.
+const (
+	CapabilityFilesystem = "fs"   // Read and write files inside of the project folder.
+	CapabilityExec       = "exec" // Run processes.
+	CapabilityEnv        = "env"  // Read environment variables.
+)
+
+// Capabilities returns the list of all known capabilities.
+func Capabilities() []string {
+	return []string{CapabilityFilesystem, CapabilityExec, CapabilityEnv}
+}
+
+// IsCapability reports whether name is a known capability.
+func IsCapability(name string) bool {
+	for _, capability := range Capabilities() {
+		if capability == name {
+			return true
+		}
+	}
+	return false
+}
+
+// CapabilityError is returned when a sandboxed plugin uses a capability that was not granted to it.
+type CapabilityError struct {
+	Capability string
+	Reason     string
+}
+
+func (e *CapabilityError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("capability '%s' denied, %s", e.Capability, e.Reason)
+	}
+	return fmt.Sprintf("capability '%s' was not granted to the plugin", e.Capability)
+}
+
+// safeLibs are the lua libraries that are opened in sandbox mode. They give no access to the host system.
+var safeLibs = []struct { //nolint:gochecknoglobals
+	name string
+	fn   lua.LGFunction
+}{
+	{lua.LoadLibName, lua.OpenPackage},
+	{lua.BaseLibName, lua.OpenBase},
+	{lua.TabLibName, lua.OpenTable},
+	{lua.StringLibName, lua.OpenString},
+	{lua.MathLibName, lua.OpenMath},
+	{lua.CoroutineLibName, lua.OpenCoroutine},
+}
+
+// newState returns a new lua state. In sandbox mode only safe libraries are opened and the base functions that load
+// files are removed. Modules can only be required from the preloaded ones. Otherwise all libraries are opened and
+// treat the project folder as the working directory.
+func newState(env *Env) *lua.LState {
+	if !env.Sandboxed {
+		L := lua.NewState()
+		bindWorkingDir(L, env.Data.Path)
+		return L
+	}
+
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	for _, lib := range safeLibs {
+		L.Push(L.NewFunction(lib.fn))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	for _, name := range []string{"dofile", "loadfile"} {
+		L.SetGlobal(name, lua.LNil)
+	}
+	if pkg, ok := L.GetGlobal(lua.LoadLibName).(*lua.LTable); ok {
+		pkg.RawSetString("path", lua.LString(""))
+		pkg.RawSetString("cpath", lua.LString(""))
+	}
+
+	// Offer the time related functions of the os library only
+	L.Push(L.NewFunction(lua.OpenOs))
+	L.Push(lua.LString(lua.OsLibName))
+	L.Call(1, 0)
+	fullOsLib := L.GetGlobal(lua.OsLibName)
+	osLib := L.NewTable()
+	for _, name := range []string{"clock", "date", "difftime", "time"} {
+		osLib.RawSetString(name, L.GetField(fullOsLib, name))
+	}
+	L.SetGlobal(lua.OsLibName, osLib)
+	L.SetField(L.GetField(L.Get(lua.RegistryIndex), "_LOADED"), lua.OsLibName, osLib)
+	return L
+}
+
+// allows reports whether the plugin may use the given capability. Plugins that don't run in sandbox mode have every
+// capability.
+func (e *Env) allows(capability string) bool {
+	if !e.Sandboxed {
+		return true
+	}
+	for _, granted := range e.Capabilities {
+		if granted == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPath makes sure that a sandboxed plugin only accesses paths inside of the project folder. Symlinks are resolved
+// so that they can't be used to escape the project folder.
+func (e *Env) checkPath(path string) error {
+	if !e.Sandboxed {
+		return nil
+	}
+
+	root, err := evalExisting(e.Data.Path)
+	if err != nil {
+		return err
+	}
+	resolved, err := evalExisting(path)
+	if err != nil {
+		return err
+	}
+	if !isSubPath(root, resolved) {
+		return &CapabilityError{
+			Capability: CapabilityFilesystem,
+			Reason:     fmt.Sprintf("path %s is outside of the project folder", path),
+		}
+	}
+	return nil
+}
+
+// evalExisting resolves the symlinks of the longest existing prefix of path and appends the remaining elements.
+func evalExisting(path string) (string, error) {
+	path = filepath.Clean(path)
+	resolved, err := filepath.EvalSymlinks(path)
+	if err == nil {
+		return resolved, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+	parent := filepath.Dir(path)
+	if parent == path {
+		return path, nil
+	}
+	resolvedParent, err := evalExisting(parent)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(resolvedParent, filepath.Base(path)), nil
+}
+
+// isSubPath reports whether path lies inside of root. Both paths are compared lexically.
+func isSubPath(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,80 @@
+package plugin
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// libFunction is a function of a standard lua library whose leading arguments are rewritten.
+type libFunction struct {
+	lib  string
+	name string
+	args int // Number of leading arguments to rewrite.
+}
+
+// pathFunctions are the functions of the standard lua libraries that take file paths.
+var pathFunctions = []libFunction{ //nolint:gochecknoglobals
+	{lib: lua.BaseLibName, name: "dofile", args: 1},
+	{lib: lua.BaseLibName, name: "loadfile", args: 1},
+	{lib: lua.IoLibName, name: "open", args: 1},
+	{lib: lua.IoLibName, name: "lines", args: 1},
+	{lib: lua.OsLibName, name: "remove", args: 1},
+	{lib: lua.OsLibName, name: "rename", args: 2},
+}
+
+// commandFunctions are the functions of the standard lua libraries that run shell commands.
+var commandFunctions = []libFunction{ //nolint:gochecknoglobals
+	{lib: lua.IoLibName, name: "popen", args: 1},
+	{lib: lua.OsLibName, name: "execute", args: 1},
+}
+
+// bindWorkingDir makes the standard lua libraries behave as if dir was the working directory of the process. Relative
+// paths are resolved relative to dir and shell commands are run inside of dir. This way plugins don't depend on the
+// working directory of proji, which is shared by all projects that are created at the same time.
+func bindWorkingDir(L *lua.LState, dir string) {
+	wrapFunctions(L, pathFunctions, func(path string) string {
+		if path == "" || filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(dir, path)
+	})
+	wrapFunctions(L, commandFunctions, func(command string) string {
+		if command == "" {
+			return command
+		}
+		if runtime.GOOS == "windows" {
+			return `cd /d "` + dir + `" && ` + command
+		}
+		return "cd '" + strings.ReplaceAll(dir, "'", `'\''`) + "' && " + command
+	})
+}
+
+// wrapFunctions replaces the given library functions with functions that pass their leading string arguments through
+// rewrite before calling the original function. Functions of libraries that are not loaded are ignored.
+func wrapFunctions(L *lua.LState, functions []libFunction, rewrite func(string) string) {
+	for _, function := range functions {
+		table, ok := L.GetGlobal(function.lib).(*lua.LTable)
+		if !ok {
+			continue
+		}
+		original, ok := table.RawGetString(function.name).(*lua.LFunction)
+		if !ok {
+			continue
+		}
+		args := function.args
+		table.RawSetString(function.name, L.NewFunction(func(L *lua.LState) int {
+			for i := 1; i <= args; i++ {
+				if arg, ok := L.Get(i).(lua.LString); ok {
+					L.Replace(i, lua.LString(rewrite(string(arg))))
+				}
+			}
+			top := L.GetTop()
+			L.Insert(original, 1)
+			L.Call(top, lua.MultRet)
+			return L.GetTop()
+		}))
+	}
+}
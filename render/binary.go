@@ -0,0 +1,25 @@
+package render
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+// sniffLen is the number of bytes that are inspected when checking if content is binary. Same as git's heuristic.
+const sniffLen = 8000
+
+// IsBinary reports whether the given content looks like binary data. Content is considered binary if it contains a
+// NUL byte or is not valid UTF-8 within the first few kilobytes.
+func IsBinary(content []byte) bool {
+	if len(content) > sniffLen {
+		content = content[:sniffLen]
+		// Don't mistake a multi-byte rune that was cut in half for invalid UTF-8.
+		for i := 0; i < utf8.UTFMax && len(content) > 0 && !utf8.Valid(content); i++ {
+			content = content[:len(content)-1]
+		}
+	}
+	if bytes.IndexByte(content, 0) != -1 {
+		return true
+	}
+	return !utf8.Valid(content)
+}
@@ -0,0 +1,172 @@
+package render
+
+import (
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+)
+
+// FuncMap returns the helper functions that are available inside of templates.
+func FuncMap() template.FuncMap {
+	return template.FuncMap{
+		// Case conversions
+		"lower":  strings.ToLower,
+		"upper":  strings.ToUpper,
+		"title":  toTitle,
+		"camel":  toCamel,
+		"pascal": toPascal,
+		"snake":  toSnake,
+		"kebab":  toKebab,
+
+		// String helpers
+		"trim":      strings.TrimSpace,
+		"replace":   replace,
+		"contains":  contains,
+		"hasPrefix": hasPrefix,
+		"hasSuffix": hasSuffix,
+		"split":     split,
+		"join":      join,
+		"default":   defaultValue,
+
+		// Date helpers
+		"now":  time.Now,
+		"date": formatDate,
+		"year": year,
+	}
+}
+
+// words splits a string into its words. Words are separated by any non letter and non digit character and by
+// lower-to-upper case transitions; e.g. "my-newProject" becomes ["my", "new", "Project"].
+func words(s string) []string {
+	var result []string
+	var current []rune
+	runes := []rune(s)
+
+	flush := func() {
+		if len(current) > 0 {
+			result = append(result, string(current))
+			current = current[:0]
+		}
+	}
+
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		if i > 0 && unicode.IsUpper(r) && len(current) > 0 {
+			prev := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+	return result
+}
+
+// capitalize returns s with its first letter in upper case and the rest in lower case.
+func capitalize(s string) string {
+	runes := []rune(strings.ToLower(s))
+	if len(runes) == 0 {
+		return ""
+	}
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+func toTitle(s string) string {
+	parts := words(s)
+	for i, part := range parts {
+		parts[i] = capitalize(part)
+	}
+	return strings.Join(parts, " ")
+}
+
+func toCamel(s string) string {
+	parts := words(s)
+	for i, part := range parts {
+		if i == 0 {
+			parts[i] = strings.ToLower(part)
+			continue
+		}
+		parts[i] = capitalize(part)
+	}
+	return strings.Join(parts, "")
+}
+
+func toPascal(s string) string {
+	parts := words(s)
+	for i, part := range parts {
+		parts[i] = capitalize(part)
+	}
+	return strings.Join(parts, "")
+}
+
+func toSnake(s string) string {
+	return strings.ToLower(strings.Join(words(s), "_"))
+}
+
+func toKebab(s string) string {
+	return strings.ToLower(strings.Join(words(s), "-"))
+}
+
+// The following helpers take the piped value as their last argument so that they can be used in pipelines;
+// e.g. {{ .Name | replace "-" "_" }}.
+
+func replace(old, new, s string) string {
+	return strings.ReplaceAll(s, old, new)
+}
+
+func contains(substr, s string) bool {
+	return strings.Contains(s, substr)
+}
+
+func hasPrefix(prefix, s string) bool {
+	return strings.HasPrefix(s, prefix)
+}
+
+func hasSuffix(suffix, s string) bool {
+	return strings.HasSuffix(s, suffix)
+}
+
+func split(sep, s string) []string {
+	return strings.Split(s, sep)
+}
+
+func join(sep string, elems []string) string {
+	return strings.Join(elems, sep)
+}
+
+// defaultValue returns def if value is the zero value of its type.
+func defaultValue(def, value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return def
+	case string:
+		if v == "" {
+			return def
+		}
+	case bool:
+		if !v {
+			return def
+		}
+	case int:
+		if v == 0 {
+			return def
+		}
+	}
+	return value
+}
+
+// formatDate formats t with the given layout. The layout follows the rules of Go's time package.
+func formatDate(layout string, t time.Time) string {
+	return t.Format(layout)
+}
+
+func year(t time.Time) int {
+	return t.Year()
+}
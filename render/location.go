@@ -0,0 +1,43 @@
+package render
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// namePattern matches references of the project name in templates; e.g. {{ .Name }} but not {{ .Package.Name }}.
+var namePattern = regexp.MustCompile(`(^|[^\w.])\.Name\b`)
+
+// Location renders the location template of a project and returns the path of the project folder. A location is a
+// folder that projects are created in, e.g. ~/src/{{ .Package.Label }}, so the project name is appended to it. If the
+// location references the project name itself, e.g. ~/src/{{ .Name }}-{{ year now }}, it is the path of the project
+// folder. A leading ~ is replaced with the home folder of the current user. The path of the project is not known yet
+// and thus not available inside of the template.
+func Location(location string, data *Data) (string, error) {
+	path, err := Text("location", location, data)
+	if err != nil {
+		return "", err
+	}
+
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, path[1:])
+	}
+	if !namePattern.MatchString(location) {
+		path = filepath.Join(path, data.Name)
+	}
+	return filepath.Clean(path), nil
+}
+
+// Validate parses the given template text and returns the syntax error if it is invalid. Name is used in error
+// messages only.
+func Validate(name, text string) error {
+	_, err := template.New(name).Funcs(FuncMap()).Parse(text)
+	return err
+}
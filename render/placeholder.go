@@ -0,0 +1,60 @@
+package render
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// placeholderPattern matches placeholders in paths like __PROJECT_NAME__. Placeholders are upper case so that common
+// dunder names like __main__.py or __init__.py are left alone.
+var placeholderPattern = regexp.MustCompile(`__([A-Z][A-Z0-9_]*?)__`)
+
+// UnknownPlaceholderError represents an error for the case that a path contains a placeholder for which no value is
+// known.
+type UnknownPlaceholderError struct {
+	Placeholder string
+	Path        string
+}
+
+func (e *UnknownPlaceholderError) Error() string {
+	return fmt.Sprintf("unknown placeholder '__%s__' in path '%s'", e.Placeholder, e.Path)
+}
+
+// Placeholders returns the values of all placeholders that can be used in paths, keyed by the placeholder name without
+// its surrounding underscores. Variables are available by their upper case name; e.g. the variable license becomes
+// __LICENSE__. Built-in placeholders take precedence over variables.
+func (d *Data) Placeholders() map[string]string {
+	placeholders := make(map[string]string, len(d.Vars)+3)
+	for name, value := range d.Vars {
+		placeholders[strings.ToUpper(name)] = fmt.Sprint(value)
+	}
+	placeholders["PROJECT_NAME"] = d.Name
+	if d.Package != nil {
+		placeholders["PACKAGE_NAME"] = d.Package.Name
+		placeholders["PACKAGE_LABEL"] = d.Package.Label
+	}
+	return placeholders
+}
+
+// Path replaces all placeholders in the given path with their values. Returns an UnknownPlaceholderError if the path
+// contains a placeholder for which no value is known.
+func Path(path string, data *Data) (string, error) {
+	placeholders := data.Placeholders()
+	var err error
+	resolved := placeholderPattern.ReplaceAllStringFunc(path, func(match string) string {
+		name := placeholderPattern.FindStringSubmatch(match)[1]
+		value, ok := placeholders[name]
+		if !ok {
+			if err == nil {
+				err = &UnknownPlaceholderError{Placeholder: name, Path: path}
+			}
+			return match
+		}
+		return value
+	})
+	if err != nil {
+		return "", err
+	}
+	return resolved, nil
+}
@@ -0,0 +1,144 @@
+// Package render implements the rendering of template files. Templates are rendered with Go's text/template package
+// and have access to a set of project related values and helper functions.
+package render
+
+import (
+	"bytes"
+	"os"
+	"os/user"
+	"path/filepath"
+	"text/template"
+	"time"
+)
+
+// Data holds the values that are accessible from inside of templates.
+type Data struct {
+	Name    string       // Name of the project.
+	Path    string       // Absolute path of the project.
+	Package *PackageData // Package that the project is based on.
+	Date    time.Time    // Date of the project creation.
+	User    string       // Name of the OS user that creates the project.
+	Vars    Vars         // Values of the package variables.
+}
+
+// Vars maps variable names to their values.
+type Vars map[string]interface{}
+
+// PackageData holds the package related values that are accessible from inside of templates.
+type PackageData struct {
+	Name  string
+	Label string
+}
+
+// NewData returns a new data instance for the given project, package and variable values. Date is set to the current
+// time, truncated to the second, and user to the current OS user.
+func NewData(projectName, projectPath, packageName, packageLabel string, vars Vars) *Data {
+	if vars == nil {
+		vars = make(Vars)
+	}
+	return &Data{
+		Name: projectName,
+		Path: projectPath,
+		Package: &PackageData{
+			Name:  packageName,
+			Label: packageLabel,
+		},
+		Date: time.Now().Truncate(time.Second),
+		User: currentUser(),
+		Vars: vars,
+	}
+}
+
+// Text renders the given template text with the given data and returns the result. Name is used in error messages
+// only.
+func Text(name, text string, data *Data) (string, error) {
+	tmpl, err := template.New(name).Funcs(FuncMap()).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, data)
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// File renders the template file src and writes the result to dst. Missing parent directories of dst get created
+// and the file mode of src is preserved. Binary files are not rendered but copied untouched. Existing files are
+// overwritten.
+func File(src, dst string, data *Data) error {
+	return new(Writer).File(src, dst, data)
+}
+
+// Inline renders the given template text and writes the result to dst. Missing parent directories of dst get created.
+// Existing files are overwritten.
+func Inline(text, dst string, data *Data) error {
+	return new(Writer).Inline(text, dst, data)
+}
+
+// Dir renders all files found in the template directory src into the directory dst. See Writer.Dir for details.
+// Existing files are overwritten.
+func Dir(src, dst string, data *Data) error {
+	return new(Writer).Dir(src, dst, data)
+}
+
+// Entry is a file, folder or symbolic link that Dir creates.
+type Entry struct {
+	Path      string // Path relative to the destination folder with resolved placeholders.
+	Source    string // Path of the source relative to the source folder.
+	IsDir     bool
+	IsSymlink bool
+}
+
+// Entries returns the entries that Dir would create for the folder src, in the order it would create them. If data is
+// nil, the entries are the ones that Writer.CopyDir would create. Nothing is written.
+func Entries(src string, data *Data) ([]Entry, error) {
+	entries := make([]Entry, 0)
+	err := filepath.Walk(src, func(currentPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		source, err := filepath.Rel(src, currentPath)
+		if err != nil {
+			return err
+		}
+		if source == "." {
+			return nil
+		}
+		relPath := source
+		if data != nil {
+			relPath, err = Path(source, data)
+			if err != nil {
+				return err
+			}
+		}
+		entries = append(entries, Entry{
+			Path:      relPath,
+			Source:    source,
+			IsDir:     info.IsDir(),
+			IsSymlink: info.Mode()&os.ModeSymlink != 0,
+		})
+		return nil
+	})
+	return entries, err
+}
+
+// Symlink creates a symbolic link at path that points to target. Missing parent directories of path get created.
+// Existing files are replaced.
+func Symlink(target, path string) error {
+	return new(Writer).Symlink(target, path)
+}
+
+// currentUser returns the username of the current OS user. Falls back to the USER and USERNAME environment variables
+// if the lookup fails.
+func currentUser() string {
+	u, err := user.Current()
+	if err == nil {
+		return u.Username
+	}
+	if name, ok := os.LookupEnv("USER"); ok {
+		return name
+	}
+	return os.Getenv("USERNAME")
+}
@@ -0,0 +1,295 @@
+package render
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testData() *Data {
+	return &Data{
+		Name:    "my-project",
+		Path:    "/tmp/my-project",
+		Package: &PackageData{Name: "python", Label: "py"},
+		Date:    time.Date(2020, 7, 12, 0, 0, 0, 0, time.UTC),
+		User:    "tester",
+		Vars:    Vars{"license": "MIT", "use_docker": true},
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{name: "Plain text", text: "hello world", want: "hello world"},
+		{name: "Project name", text: "# {{ .Name }}", want: "# my-project"},
+		{name: "Package label", text: "{{ .Package.Label }}", want: "py"},
+		{name: "Pascal case", text: "{{ pascal .Name }}", want: "MyProject"},
+		{name: "Pipeline", text: "{{ .Name | replace \"-\" \"_\" | upper }}", want: "MY_PROJECT"},
+		{name: "Date", text: "(c) {{ date \"2006\" .Date }} {{ .User }}", want: "(c) 2020 tester"},
+		{name: "Variable", text: "License: {{ .Vars.license }}", want: "License: MIT"},
+		{name: "Bool variable", text: "{{ if .Vars.use_docker }}docker{{ end }}", want: "docker"},
+		{name: "Unknown variable", text: "{{ .Vars.unknown }}", wantErr: true},
+		{name: "Default", text: "{{ default \"none\" \"\" }}", want: "none"},
+		{name: "Unknown field", text: "{{ .Unknown }}", wantErr: true},
+		{name: "Syntax error", text: "{{ .Name ", wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := Text(test.name, test.text, testData())
+		if test.wantErr {
+			assert.Error(t, err, test.name)
+			continue
+		}
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.want, got, test.name)
+	}
+}
+
+func TestCaseConversions(t *testing.T) {
+	tests := []struct {
+		input  string
+		camel  string
+		pascal string
+		snake  string
+		kebab  string
+		title  string
+	}{
+		{input: "my-project", camel: "myProject", pascal: "MyProject", snake: "my_project", kebab: "my-project", title: "My Project"},
+		{input: "myNewProject", camel: "myNewProject", pascal: "MyNewProject", snake: "my_new_project", kebab: "my-new-project", title: "My New Project"},
+		{input: "HTTPServer v2", camel: "httpServerV2", pascal: "HttpServerV2", snake: "http_server_v2", kebab: "http-server-v2", title: "Http Server V2"},
+		{input: "", camel: "", pascal: "", snake: "", kebab: "", title: ""},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.camel, toCamel(test.input), test.input)
+		assert.Equal(t, test.pascal, toPascal(test.input), test.input)
+		assert.Equal(t, test.snake, toSnake(test.input), test.input)
+		assert.Equal(t, test.kebab, toKebab(test.input), test.input)
+		assert.Equal(t, test.title, toTitle(test.input), test.input)
+	}
+}
+
+func TestIsBinary(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		want    bool
+	}{
+		{name: "Empty", content: []byte{}, want: false},
+		{name: "Text", content: []byte("package main\n"), want: false},
+		{name: "UTF-8 text", content: []byte("Grüße, 世界"), want: false},
+		{name: "NUL byte", content: []byte{'a', 0, 'b'}, want: true},
+		{name: "PNG header", content: []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}, want: true},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, IsBinary(test.content), test.name)
+	}
+}
+
+func TestDir(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "proji-render")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	src := filepath.Join(tmpDir, "src")
+	dst := filepath.Join(tmpDir, "dst")
+	assert.NoError(t, os.MkdirAll(filepath.Join(src, "docs"), os.ModePerm))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(src, "README.md"), []byte("# {{ .Name }}\n"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(src, "docs", "logo.bin"), []byte{'{', '{', 0, '}', '}'}, 0644))
+
+	assert.NoError(t, Dir(src, dst, testData()))
+
+	readme, err := ioutil.ReadFile(filepath.Join(dst, "README.md"))
+	assert.NoError(t, err)
+	assert.Equal(t, "# my-project\n", string(readme))
+
+	logo, err := ioutil.ReadFile(filepath.Join(dst, "docs", "logo.bin"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{'{', '{', 0, '}', '}'}, logo)
+}
+
+func TestCopy(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "proji-render")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	workflow := "steps:\n  - run: echo ${{ secrets.TOKEN }}\n"
+	src := filepath.Join(tmpDir, "src")
+	dst := filepath.Join(tmpDir, "dst")
+	assert.NoError(t, os.MkdirAll(filepath.Join(src, "__PROJECT_NAME__"), os.ModePerm))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(src, "__PROJECT_NAME__", "ci.yml"), []byte(workflow), 0755))
+
+	// Rendering fails, copying keeps the file as is
+	w := new(Writer)
+	assert.Error(t, w.Dir(src, dst, testData()))
+	assert.NoError(t, os.RemoveAll(dst))
+	assert.NoError(t, w.CopyDir(src, dst))
+	ci := filepath.Join(dst, "__PROJECT_NAME__", "ci.yml")
+	content, err := ioutil.ReadFile(ci)
+	assert.NoError(t, err)
+	assert.Equal(t, workflow, string(content))
+	info, err := os.Stat(ci)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+
+	assert.NoError(t, w.CopyFile(ci, filepath.Join(dst, "ci.yml")))
+	content, err = ioutil.ReadFile(filepath.Join(dst, "ci.yml"))
+	assert.NoError(t, err)
+	assert.Equal(t, workflow, string(content))
+
+	entries, err := Entries(src, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "__PROJECT_NAME__", entries[0].Path)
+}
+
+func TestPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{name: "No placeholder", path: "src/main.go", want: "src/main.go"},
+		{name: "Dunder names", path: "__PROJECT_NAME__/__main__.py", want: "my-project/__main__.py"},
+		{name: "Multiple placeholders", path: "__PROJECT_NAME__/__PROJECT_NAME__.py", want: "my-project/my-project.py"},
+		{name: "Package label", path: "configs/__PACKAGE_LABEL__.toml", want: "configs/py.toml"},
+		{name: "Variable", path: "LICENSE-__LICENSE__", want: "LICENSE-MIT"},
+		{name: "Unknown placeholder", path: "__UNKNOWN__/main.py", wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := Path(test.path, testData())
+		if test.wantErr {
+			assert.IsType(t, &UnknownPlaceholderError{}, err, test.name)
+			continue
+		}
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.want, got, test.name)
+	}
+}
+
+func TestDirPreservesModesAndSymlinks(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "proji-render")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	src := filepath.Join(tmpDir, "src")
+	dst := filepath.Join(tmpDir, "dst")
+	assert.NoError(t, os.MkdirAll(src, os.ModePerm))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(src, "run.sh"), []byte("#!/bin/sh\necho {{ .Name }}\n"), 0755))
+	assert.NoError(t, os.Symlink("run.sh", filepath.Join(src, "start")))
+
+	assert.NoError(t, Dir(src, dst, testData()))
+
+	info, err := os.Stat(filepath.Join(dst, "run.sh"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+
+	target, err := os.Readlink(filepath.Join(dst, "start"))
+	assert.NoError(t, err)
+	assert.Equal(t, "run.sh", target)
+}
+
+func TestEntries(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "proji-render")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "__PROJECT_NAME__"), os.ModePerm))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(tmpDir, "__PROJECT_NAME__", "main.py"), nil, 0644))
+
+	entries, err := Entries(tmpDir, testData())
+	assert.NoError(t, err)
+	assert.Equal(t, []Entry{
+		{Path: "my-project", Source: "__PROJECT_NAME__", IsDir: true},
+		{Path: filepath.Join("my-project", "main.py"), Source: filepath.Join("__PROJECT_NAME__", "main.py")},
+	}, entries)
+}
+
+func TestWriterConflicts(t *testing.T) {
+	tests := []struct {
+		name       string
+		resolution string
+		want       string
+		backup     string
+	}{
+		{name: "Skip", resolution: ConflictSkip, want: "existing\n"},
+		{name: "Overwrite", resolution: ConflictOverwrite, want: "# my-project\n"},
+		{name: "Backup", resolution: ConflictBackup, want: "# my-project\n", backup: "existing\n"},
+	}
+
+	for _, test := range tests {
+		tmpDir, err := ioutil.TempDir("", "proji-render")
+		assert.NoError(t, err, test.name)
+
+		readme := filepath.Join(tmpDir, "README.md")
+		unchanged := filepath.Join(tmpDir, "LICENSE")
+		assert.NoError(t, ioutil.WriteFile(readme, []byte("existing\n"), 0644), test.name)
+		assert.NoError(t, ioutil.WriteFile(unchanged, []byte("MIT\n"), 0644), test.name)
+
+		conflicts := make([]string, 0)
+		w := &Writer{Conflict: func(path string, current, content []byte) (string, error) {
+			conflicts = append(conflicts, path)
+			assert.Equal(t, "existing\n", string(current), test.name)
+			assert.Equal(t, "# my-project\n", string(content), test.name)
+			return test.resolution, nil
+		}}
+		assert.NoError(t, w.Inline("# {{ .Name }}\n", readme, testData()), test.name)
+		assert.NoError(t, w.Inline("{{ .Vars.license }}\n", unchanged, testData()), test.name)
+		assert.Equal(t, []string{readme}, conflicts, test.name)
+
+		content, err := ioutil.ReadFile(readme)
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.want, string(content), test.name)
+		assert.Equal(t, test.resolution == ConflictSkip, w.IsSkipped(readme), test.name)
+
+		if test.backup != "" {
+			assert.Equal(t, []Backup{{Path: readme, Backup: readme + ".bak"}}, w.Backups(), test.name)
+			content, err = ioutil.ReadFile(readme + ".bak")
+			assert.NoError(t, err, test.name)
+			assert.Equal(t, test.backup, string(content), test.name)
+		}
+		os.RemoveAll(tmpDir)
+	}
+}
+
+func TestLocation(t *testing.T) {
+	home, err := os.UserHomeDir()
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		location string
+		want     string
+		wantErr  bool
+	}{
+		{name: "Plain folder", location: "/src/go", want: "/src/go/my-project"},
+		{name: "Relative folder", location: "src/", want: "src/my-project"},
+		{name: "Home folder", location: "~/src", want: filepath.Join(home, "src", "my-project")},
+		{name: "Package label", location: "/src/{{ .Package.Label }}", want: "/src/py/my-project"},
+		{name: "Package name is no project name", location: "/src/{{ .Package.Name }}", want: "/src/python/my-project"},
+		{name: "Project name", location: "/src/{{.Package.Label}}/{{.Name}}", want: "/src/py/my-project"},
+		{name: "Project name in function", location: "/src/{{ snake .Name }}", want: "/src/my_project"},
+		{name: "Variable", location: "/src/{{ .Vars.license }}", want: "/src/MIT/my-project"},
+		{name: "Unknown variable", location: "/src/{{ .Vars.unknown }}", wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := Location(test.location, testData())
+		if test.wantErr {
+			assert.Error(t, err, test.name)
+			continue
+		}
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.want, got, test.name)
+	}
+}
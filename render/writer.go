@@ -0,0 +1,327 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// Resolutions of a conflict with an existing file.
+const (
+	ConflictSkip      = "skip"      // Keep the existing file.
+	ConflictOverwrite = "overwrite" // Replace the existing file.
+	ConflictBackup    = "backup"    // Rename the existing file and write the new one.
+)
+
+// ConflictStrategies returns the list of all resolutions of a conflict.
+func ConflictStrategies() []string {
+	return []string{ConflictSkip, ConflictOverwrite, ConflictBackup}
+}
+
+// ConflictFunc decides how the conflict with the existing file at path is resolved. Current is the content of the
+// existing file, or the target if it is a symbolic link, and content is what would be written instead. It has to
+// return one of ConflictSkip, ConflictOverwrite and ConflictBackup.
+type ConflictFunc func(path string, current, content []byte) (string, error)
+
+// Backup is an existing file that was renamed to make room for a new one.
+type Backup struct {
+	Path   string // Path of the replaced file.
+	Backup string // Path the replaced file was renamed to.
+}
+
+// Writer writes rendered templates to disk. Whenever an existing file would be replaced with a different content,
+// Conflict decides what happens to it. A writer without a Conflict function overwrites existing files. Existing
+// folders are never a conflict, files are added to them.
+//
+// A writer is safe for concurrent use; calls of Conflict are serialized.
+type Writer struct {
+	Conflict ConflictFunc
+
+	mu      sync.Mutex
+	skipped []string
+	backups []Backup
+}
+
+// Skipped returns the paths of the existing files that were kept because of a conflict.
+func (w *Writer) Skipped() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string(nil), w.skipped...)
+}
+
+// Backups returns the existing files that were renamed because of a conflict.
+func (w *Writer) Backups() []Backup {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]Backup(nil), w.backups...)
+}
+
+// IsSkipped reports whether the existing file at path was kept because of a conflict.
+func (w *Writer) IsSkipped(path string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, skipped := range w.skipped {
+		if skipped == path {
+			return true
+		}
+	}
+	return false
+}
+
+// File renders the template file src and writes the result to dst. Missing parent directories of dst get created
+// and the file mode of src is preserved. Binary files are not rendered but copied untouched.
+func (w *Writer) File(src, dst string, data *Data) error {
+	return w.file(src, dst, data)
+}
+
+// CopyFile copies the file src to dst like File, but without rendering it.
+func (w *Writer) CopyFile(src, dst string) error {
+	return w.file(src, dst, nil)
+}
+
+// file writes the file src to dst. It is rendered with data unless data is nil or the file is binary.
+func (w *Writer) file(src, dst string, data *Data) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	content, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	if data != nil && !IsBinary(content) {
+		rendered, err := Text(filepath.Base(src), string(content), data)
+		if err != nil {
+			return err
+		}
+		content = []byte(rendered)
+	}
+	written, err := w.write(dst, content)
+	if err != nil || !written {
+		return err
+	}
+	// Chmod explicitly, the mode passed on creation is subject to the umask and ignored for existing files.
+	return os.Chmod(dst, info.Mode().Perm())
+}
+
+// Inline renders the given template text and writes the result to dst. Missing parent directories of dst get created.
+func (w *Writer) Inline(text, dst string, data *Data) error {
+	rendered, err := Text(filepath.Base(dst), text, data)
+	if err != nil {
+		return err
+	}
+	_, err = w.write(dst, []byte(rendered))
+	return err
+}
+
+// WriteFile writes content to the file at path. Missing parent directories get created.
+func (w *Writer) WriteFile(path string, content []byte) error {
+	_, err := w.write(path, content)
+	return err
+}
+
+// Dir renders all files found in the template directory src into the directory dst. The structure of src is preserved
+// while placeholders in file and folder names get replaced. File and folder modes are preserved and symbolic links
+// are recreated instead of followed. The modes of folders that already existed are left untouched.
+func (w *Writer) Dir(src, dst string, data *Data) error {
+	return w.dir(src, dst, data)
+}
+
+// CopyDir copies the directory src to dst like Dir, but without rendering the files or the placeholders in their
+// names.
+func (w *Writer) CopyDir(src, dst string) error {
+	return w.dir(src, dst, nil)
+}
+
+// dir writes all files found in the directory src into the directory dst. Files and names are rendered with data
+// unless data is nil.
+func (w *Writer) dir(src, dst string, data *Data) error {
+	// Folder modes are applied after all files were written. Otherwise read-only folders couldn't be filled.
+	type folderMode struct {
+		path string
+		mode os.FileMode
+	}
+	folderModes := make([]folderMode, 0)
+
+	err := filepath.Walk(src, func(currentPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(src, currentPath)
+		if err != nil {
+			return err
+		}
+		if data != nil {
+			relPath, err = Path(relPath, data)
+			if err != nil {
+				return err
+			}
+		}
+		target := filepath.Join(dst, relPath)
+
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			return w.copySymlink(currentPath, target)
+		case info.IsDir():
+			if _, err := os.Lstat(target); os.IsNotExist(err) {
+				folderModes = append(folderModes, folderMode{path: target, mode: info.Mode().Perm()})
+			}
+			return os.MkdirAll(target, os.ModePerm)
+		default:
+			return w.file(currentPath, target, data)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	// Apply in reverse order so that child folders are handled before their parents
+	for i := len(folderModes) - 1; i >= 0; i-- {
+		err = os.Chmod(folderModes[i].path, folderModes[i].mode)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copySymlink creates a symbolic link at dst that points to the same target as the symbolic link src.
+func (w *Writer) copySymlink(src, dst string) error {
+	target, err := os.Readlink(src)
+	if err != nil {
+		return err
+	}
+	return w.Symlink(target, dst)
+}
+
+// Symlink creates a symbolic link at path that points to target. Missing parent directories of path get created.
+func (w *Writer) Symlink(target, path string) error {
+	err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	current, exists, err := existingContent(path)
+	if err != nil {
+		return err
+	}
+	if exists {
+		if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSymlink != 0 && string(current) == target {
+			return nil
+		}
+		replace, err := w.resolve(path, current, []byte(target))
+		if err != nil || !replace {
+			return err
+		}
+	}
+	return os.Symlink(target, path)
+}
+
+// write writes content to the file at path. Missing parent directories get created. The returned bool is false if an
+// existing file was kept because of a conflict.
+func (w *Writer) write(path string, content []byte) (bool, error) {
+	err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return false, err
+	}
+
+	current, exists, err := existingContent(path)
+	if err != nil {
+		return false, err
+	}
+	if exists {
+		info, err := os.Lstat(path)
+		if err != nil {
+			return false, err
+		}
+		isSymlink := info.Mode()&os.ModeSymlink != 0
+		if !isSymlink && bytes.Equal(current, content) {
+			return true, nil
+		}
+		replace, err := w.resolve(path, current, content)
+		if err != nil || !replace {
+			return false, err
+		}
+	}
+	return true, ioutil.WriteFile(path, content, 0666)
+}
+
+// resolve resolves the conflict with the existing file at path. It returns true if the file was moved out of the way
+// and may be replaced.
+func (w *Writer) resolve(path string, current, content []byte) (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	resolution := ConflictOverwrite
+	if w.Conflict != nil {
+		var err error
+		resolution, err = w.Conflict(path, current, content)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	switch resolution {
+	case ConflictSkip:
+		w.skipped = append(w.skipped, path)
+		return false, nil
+	case ConflictOverwrite:
+		return true, os.Remove(path)
+	case ConflictBackup:
+		backup, err := backupPath(path)
+		if err != nil {
+			return false, err
+		}
+		err = os.Rename(path, backup)
+		if err != nil {
+			return false, err
+		}
+		w.backups = append(w.backups, Backup{Path: path, Backup: backup})
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown conflict resolution '%s'", resolution)
+	}
+}
+
+// existingContent returns the content of the file at path, or its target if it is a symbolic link. The returned bool
+// is false if nothing exists at path. Folders can't be replaced and result in an error.
+func existingContent(path string) ([]byte, bool, error) {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	switch {
+	case info.IsDir():
+		return nil, true, fmt.Errorf("cannot replace folder %s with a file", path)
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(path)
+		return []byte(target), true, err
+	default:
+		content, err := ioutil.ReadFile(path)
+		return content, true, err
+	}
+}
+
+// backupPath returns a path that path can be renamed to and that doesn't exist yet; e.g. main.go.bak or main.go.bak.1.
+func backupPath(path string) (string, error) {
+	backup := path + ".bak"
+	for i := 1; ; i++ {
+		_, err := os.Lstat(backup)
+		if os.IsNotExist(err) {
+			return backup, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		backup = path + ".bak." + strconv.Itoa(i)
+	}
+}
@@ -24,14 +24,71 @@ func (db *Database) Migrate() error {
 		&models.Package{},
 		&models.Plugin{},
 		&models.Project{},
+		&models.Manifest{},
+		&models.ManifestFile{},
 		&models.Template{},
+		&models.Variable{},
 	}
+	migrator := db.Connection.Migrator()
+	linkProjects := migrator.HasTable(&models.Project{}) && !migrator.HasColumn(&models.Project{}, "package_id")
 	for _, model := range modelList {
 		err := db.Connection.AutoMigrate(model)
 		if err != nil {
 			return fmt.Errorf("failed to auto-migrate model, %s", err.Error())
 		}
 	}
+	if linkProjects {
+		err := db.linkProjectPackages()
+		if err != nil {
+			return err
+		}
+	}
+	return db.dropObsoleteIndexes()
+}
+
+// linkProjectPackages sets the package of projects that were saved before projects referenced their package by id.
+// Back then saving a project stored a copy of its package under the id of the project; the project is linked to the
+// oldest package with the same label instead.
+func (db *Database) linkProjectPackages() error {
+	err := db.Connection.Exec(`
+		UPDATE projects SET package_id = (
+			SELECT MIN(original.id) FROM packages copy
+			JOIN packages original ON original.label = copy.label AND original.deleted_at IS NULL
+			WHERE copy.id = projects.id
+		)
+		WHERE package_id IS NULL OR package_id = 0`).Error
+	if err != nil {
+		return fmt.Errorf("failed to link projects to their packages, %s", err.Error())
+	}
+	return nil
+}
+
+// dropObsoleteIndexes drops indexes that were replaced in newer versions of the models. Auto-migrate only creates new
+// indexes, it never drops old ones.
+func (db *Database) dropObsoleteIndexes() error {
+	obsoleteIndexes := []struct {
+		model interface{}
+		name  string
+	}{
+		// Templates with the same path and destination were shared by all packages. Saving a package skipped the
+		// conflicting rows without linking them and shared rows can't differ in content, mode or description; every
+		// package owns its templates now.
+		{model: &models.Template{}, name: "idx_template_path_destination"},
+		{model: &models.Plugin{}, name: "idx_plugin_path"},
+		// Every version of a package is a row of its own. Databases that treat NULLs as equal in unique indexes
+		// rejected all but one version, as the versions of a package that wasn't removed share a NULL deleted_at.
+		{model: &models.Package{}, name: "idx_unq_package_label_deletedat"},
+	}
+	migrator := db.Connection.Migrator()
+	for _, index := range obsoleteIndexes {
+		if !migrator.HasIndex(index.model, index.name) {
+			continue
+		}
+		err := migrator.DropIndex(index.model, index.name)
+		if err != nil {
+			return fmt.Errorf("failed to drop obsolete index %s, %s", index.name, err.Error())
+		}
+	}
 	return nil
 }
 
@@ -0,0 +1,24 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/nikoksr/proji/storage/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrateDropsObsoleteIndexes(t *testing.T) {
+	db := newTestDatabase(t)
+	migrator := db.Connection.Migrator()
+	assert.True(t, migrator.HasIndex(&models.Package{}, "idx_unq_package_label_version_deletedat"))
+
+	// Databases of older versions of proji have a unique index on label and deleted_at only
+	err := db.Connection.Exec("CREATE UNIQUE INDEX idx_unq_package_label_deletedat ON packages(label, deleted_at)").Error
+	assert.NoError(t, err)
+	assert.NoError(t, db.Migrate())
+	assert.False(t, migrator.HasIndex(&models.Package{}, "idx_unq_package_label_deletedat"))
+	assert.True(t, migrator.HasIndex(&models.Package{}, "idx_unq_package_label_version_deletedat"))
+
+	assert.NoError(t, db.SavePackage(testPackage("go", "1.0.0")))
+	assert.NoError(t, db.SavePackage(testPackage("go", "1.1.0")))
+}
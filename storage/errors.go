@@ -17,10 +17,14 @@ func (e *UnsupportedDatabaseDialectError) Error() string {
 // PackageNotFoundError represents an error for the case that a query for a package returns a
 // gorm.ErrRecordNotFound error.
 type PackageNotFoundError struct {
-	Label string
+	Label   string
+	Version string // Empty if the query was not for a specific version.
 }
 
 func (e *PackageNotFoundError) Error() string {
+	if e.Version != "" {
+		return fmt.Sprintf("package with label '%s' and version '%s' not found", e.Label, e.Version)
+	}
 	return fmt.Sprintf("package with label '%s' not found", e.Label)
 }
 
@@ -33,13 +37,28 @@ func (e *NoPackagesFoundError) Error() string {
 
 // PackageExistsError represents an error for the case that a query for a package returns no result.
 type PackageExistsError struct {
-	Label string
+	Label   string
+	Version string
 }
 
 func (e *PackageExistsError) Error() string {
+	if e.Version != "" {
+		return fmt.Sprintf("package with label '%s' and version '%s' already exists", e.Label, e.Version)
+	}
 	return fmt.Sprintf("package with label '%s' already exists", e.Label)
 }
 
+// PackageReplaceError represents an error for the case that a package would replace the current version of a package
+// with the same label although it is not newer or changes whether its plugins run in a sandbox.
+type PackageReplaceError struct {
+	Label  string
+	Reason string
+}
+
+func (e *PackageReplaceError) Error() string {
+	return fmt.Sprintf("package with label '%s' would replace its current version, %s", e.Label, e.Reason)
+}
+
 // ProjectNotFoundError represents an error for the case that a query for a project returns a
 // gorm.ErrRecordNotFound error.
 type ProjectNotFoundError struct {
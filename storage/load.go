@@ -1,28 +1,84 @@
 package storage
 
 import (
+	"fmt"
+
 	"github.com/nikoksr/proji/storage/models"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
 type LoadService interface {
-	LoadPackage(label string) (*models.Package, error)        // LoadPackage loads a package from storage by its label.
-	LoadPackages(labels ...string) ([]*models.Package, error) // LoadPackages returns packages by the given labels. If no labels are given, all packages are loaded.
-	LoadProject(path string) (*models.Project, error)         // LoadProject loads a project from storage by its path.
-	LoadProjects(paths ...string) ([]*models.Project, error)  // LoadProjects returns projects by the given paths. If no paths are given, all projects are loaded.
+	LoadPackage(label string) (*models.Package, error)                 // LoadPackage loads the current version of a package from storage by its label.
+	LoadPackageVersion(label, version string) (*models.Package, error) // LoadPackageVersion loads a specific version of a package from storage.
+	LoadPackageHistory(label string) ([]*models.Package, error)        // LoadPackageHistory loads all versions of a package from storage.
+	LoadPackages(labels ...string) ([]*models.Package, error)          // LoadPackages returns packages by the given labels. If no labels are given, all packages are loaded.
+	LoadPackagesByPlugin(path string) ([]*models.Package, error)       // LoadPackagesByPlugin loads the current versions of all packages that use the plugin with the given path.
+	LoadPackagesByParent(label string) ([]*models.Package, error)      // LoadPackagesByParent loads the current versions of all packages that directly extend the package with the given label.
+	LoadProject(path string) (*models.Project, error)                  // LoadProject loads a project from storage by its path.
+	LoadProjects(paths ...string) ([]*models.Project, error)           // LoadProjects returns projects by the given paths. If no paths are given, all projects are loaded.
 }
 
-// LoadPackage loads a package from storage by its label.
+// LoadPackage loads the current version of a package from storage by its label. The packages it extends are resolved.
 func (db *Database) LoadPackage(label string) (*models.Package, error) {
+	pkg, err := db.loadPackage(label)
+	if err != nil {
+		return nil, err
+	}
+	return pkg, db.resolveExtends(pkg)
+}
+
+// loadPackage loads the current version of a package from storage by its label without resolving the packages it
+// extends.
+func (db *Database) loadPackage(label string) (*models.Package, error) {
 	var pkg models.Package
-	err := db.Connection.Preload(clause.Associations).First(&pkg, "label = ?", label).Error
+	err := db.Connection.Preload(clause.Associations).First(&pkg, "label = ? AND archived = ?", label, false).Error
 	if err == gorm.ErrRecordNotFound {
 		return nil, &PackageNotFoundError{Label: label}
 	}
 	return &pkg, err
 }
 
+// LoadPackageVersion loads a specific version of a package from storage, be it the current or a previous one. The
+// packages it extends are resolved; always in their current version.
+func (db *Database) LoadPackageVersion(label, version string) (*models.Package, error) {
+	var pkg models.Package
+	err := db.Connection.Preload(clause.Associations).First(&pkg, "label = ? AND version = ?", label, version).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, &PackageNotFoundError{Label: label, Version: version}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pkg, db.resolveExtends(&pkg)
+}
+
+// LoadPackageHistory loads all versions of a package from storage in the order they were saved. The packages are not
+// resolved.
+func (db *Database) LoadPackageHistory(label string) ([]*models.Package, error) {
+	var packages []*models.Package
+	err := db.Connection.Preload(clause.Associations).Order("id").Find(&packages, "label = ?", label).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(packages) == 0 {
+		return nil, &PackageNotFoundError{Label: label}
+	}
+	return packages, nil
+}
+
+// resolveExtends merges the packages that the given package extends into it.
+func (db *Database) resolveExtends(pkg *models.Package) error {
+	if pkg == nil || len(pkg.Extends) == 0 {
+		return nil
+	}
+	err := pkg.ResolveExtends(db.loadPackage)
+	if err != nil {
+		return fmt.Errorf("failed to resolve package %s, %s", pkg.Label, err.Error())
+	}
+	return nil
+}
+
 // LoadPackages loads packages by the given labels. If not labels are given, all packages are loaded.
 func (db *Database) LoadPackages(labels ...string) ([]*models.Package, error) {
 	lenLabels := len(labels)
@@ -43,21 +99,76 @@ func (db *Database) LoadPackages(labels ...string) ([]*models.Package, error) {
 // loadAllPackages loads and returns all packages found in the database.
 func (db *Database) loadAllPackages() ([]*models.Package, error) {
 	var packages []*models.Package
-	err := db.Connection.Preload(clause.Associations).Find(&packages).Error
+	err := db.Connection.Preload(clause.Associations).Find(&packages, "archived = ?", false).Error
 	if err == gorm.ErrRecordNotFound {
 		return nil, &NoPackagesFoundError{}
 	}
+	if err != nil {
+		return nil, err
+	}
+	for _, pkg := range packages {
+		err = db.resolveExtends(pkg)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return packages, nil
+}
+
+// LoadPackagesByPlugin loads the current versions of all packages that use the plugin with the given path. The path is relative to the plugins
+// folder, just like it is stored in the package.
+func (db *Database) LoadPackagesByPlugin(path string) ([]*models.Package, error) {
+	usages := db.Connection.
+		Table("package_plugins").
+		Select("package_plugins.package_id").
+		Joins("JOIN plugins ON plugins.id = package_plugins.plugin_id").
+		Where("plugins.path = ? AND plugins.deleted_at IS NULL", path)
+
+	var packages []*models.Package
+	err := db.Connection.
+		Preload(clause.Associations).
+		Where("id IN (?) AND archived = ?", usages, false).
+		Find(&packages).Error
 	return packages, err
 }
 
-// LoadProject loads a project from storage by its path.
+// LoadPackagesByParent loads the current versions of all packages that directly extend the package with the given label. The packages are not
+// resolved, so that they can be loaded even if the package with the given label is missing.
+func (db *Database) LoadPackagesByParent(label string) ([]*models.Package, error) {
+	var candidates []*models.Package
+	err := db.Connection.
+		Preload(clause.Associations).
+		Where("extends LIKE ? AND archived = ?", "%\""+label+"\"%", false).
+		Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// The pattern is only a rough filter on the json encoded labels
+	packages := make([]*models.Package, 0, len(candidates))
+	for _, pkg := range candidates {
+		for _, parent := range pkg.Extends {
+			if parent == label {
+				packages = append(packages, pkg)
+				break
+			}
+		}
+	}
+	return packages, nil
+}
+
+// LoadProject loads a project from storage by its path. The current version of its package is loaded and resolved and,
+// if the project was composed of several packages, its package is composed again.
 func (db *Database) LoadProject(path string) (*models.Project, error) {
 	var project models.Project
-	err := db.Connection.Preload(clause.Associations).First(&project, "path = ?", path).Error
+	err := preloadProjects(db.Connection).First(&project, "path = ?", path).Error
 	if err == gorm.ErrRecordNotFound {
 		return nil, &ProjectNotFoundError{Path: path}
 	}
-	return &project, err
+	if err != nil {
+		return nil, err
+	}
+	return &project, db.resolveProject(&project)
 }
 
 // LoadProjects returns projects by the given paths. If no paths are given, all projects are loaded.
@@ -80,9 +191,62 @@ func (db *Database) LoadProjects(paths ...string) ([]*models.Project, error) {
 // loadAllProjects loads and returns all projects found in the database.
 func (db *Database) loadAllProjects() ([]*models.Project, error) {
 	var projects []*models.Project
-	err := db.Connection.Preload(clause.Associations).Find(&projects).Error
+	err := preloadProjects(db.Connection).Find(&projects).Error
 	if err == gorm.ErrRecordNotFound {
 		return nil, &NoProjectsFoundError{}
 	}
-	return projects, err
+	if err != nil {
+		return nil, err
+	}
+	for _, project := range projects {
+		err = db.resolveProject(project)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return projects, nil
+}
+
+// resolveProject resolves the package of the project. Projects always follow the current version of their package; the
+// version they were generated from is recorded in their manifest. If the project was composed of several packages, the
+// other packages are loaded by their labels and layered onto its package in the original order. Packages that were
+// removed since are left out of the composition and recorded in the project's missing packages; the conflicts of the
+// composition are recorded as well.
+func (db *Database) resolveProject(project *models.Project) error {
+	if project.Package != nil && project.Package.Archived {
+		current, err := db.loadPackage(project.Package.Label)
+		if err != nil {
+			return err
+		}
+		project.Package = current
+	}
+	err := db.resolveExtends(project.Package)
+	if err != nil || project.Package == nil || len(project.Packages) < 2 {
+		return err
+	}
+	packages := []*models.Package{project.Package}
+	for _, label := range project.Packages[1:] {
+		pkg, err := db.LoadPackage(label)
+		if _, ok := err.(*PackageNotFoundError); ok {
+			project.Missing = append(project.Missing, label)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to load package %s of project %s, %s", label, project.Name, err.Error())
+		}
+		packages = append(packages, pkg)
+	}
+	project.Package, project.Conflicts = models.Compose(packages)
+	return nil
+}
+
+// preloadProjects preloads the package of projects including its templates, plugins and variables, and the manifest
+// of projects including its files.
+func preloadProjects(tx *gorm.DB) *gorm.DB {
+	return tx.
+		Preload(clause.Associations).
+		Preload("Manifest.Files").
+		Preload("Package.Templates").
+		Preload("Package.Plugins").
+		Preload("Package.Variables")
 }
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/nikoksr/proji/storage/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadComposedProject(t *testing.T) {
+	tests := []struct {
+		name          string
+		change        func(db *Database)
+		wantLabels    models.StringList // Nil if the project has no package
+		wantVersion   string            // Content of the composed VERSION template
+		wantMissing   []string
+		wantConflicts []string
+	}{
+		{
+			name:          "Unchanged",
+			change:        func(*Database) {},
+			wantLabels:    models.StringList{"go", "dk", "ci"},
+			wantVersion:   "1.0.0",
+			wantConflicts: []string{"VERSION of package go is replaced by package dk"},
+		},
+		{
+			name: "New version",
+			change: func(db *Database) {
+				assert.NoError(t, db.SavePackage(testPackage("dk", "2.0.0")))
+			},
+			wantLabels:    models.StringList{"go", "dk", "ci"},
+			wantVersion:   "2.0.0",
+			wantConflicts: []string{"VERSION of package go is replaced by package dk"},
+		},
+		{
+			name:        "Removed package",
+			change:      func(db *Database) { assert.NoError(t, db.RemovePackage("dk")) },
+			wantLabels:  models.StringList{"go", "ci"},
+			wantVersion: "1.0.0",
+			wantMissing: []string{"dk"},
+		},
+		{
+			name:   "Removed primary package",
+			change: func(db *Database) { assert.NoError(t, db.RemovePackage("go")) },
+		},
+	}
+
+	for _, test := range tests {
+		db := newTestDatabase(t)
+		for _, label := range []string{"go", "dk"} {
+			assert.NoError(t, db.SavePackage(testPackage(label, "1.0.0")), test.name)
+		}
+		ci := &models.Package{Name: "ci", Label: "ci", Templates: []*models.Template{{Destination: ".ci.yml", IsFile: true}}}
+		assert.NoError(t, db.SavePackage(ci), test.name)
+		packages := make([]*models.Package, 0, 3)
+		for _, label := range []string{"go", "dk", "ci"} {
+			pkg, err := db.LoadPackage(label)
+			assert.NoError(t, err, test.name)
+			packages = append(packages, pkg)
+		}
+		composed, _ := models.Compose(packages)
+		assert.NoError(t, db.SaveProject(models.NewProject("my-project", "/tmp/my-project", composed)), test.name)
+
+		test.change(db)
+		project, err := db.LoadProject("/tmp/my-project")
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, models.StringList{"go", "dk", "ci"}, project.Packages, test.name)
+		assert.Equal(t, test.wantMissing, project.Missing, test.name)
+		if test.wantLabels == nil {
+			assert.Nil(t, project.Package, test.name)
+			continue
+		}
+		assert.Equal(t, test.wantLabels, project.Package.Labels(), test.name)
+		if len(test.wantConflicts) > 0 {
+			assert.Equal(t, test.wantConflicts, project.Conflicts, test.name)
+		} else {
+			assert.Empty(t, project.Conflicts, test.name)
+		}
+		for _, template := range project.Package.Templates {
+			if template.Destination == "VERSION" {
+				assert.Equal(t, test.wantVersion, template.Content, test.name)
+			}
+		}
+	}
+}
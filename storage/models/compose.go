@@ -0,0 +1,84 @@
+package models
+
+import (
+	"fmt"
+)
+
+// Compose layers the packages onto each other in the given order and returns the package that results from it. The
+// packages must be resolved already; see ResolveExtends. The first package is the primary package: the composed
+// package takes its ID, name, label and description, which makes it the package that projects are stored with and that
+// templates refer to. The projects root is taken from the first package that sets one.
+//
+// Templates, plugins and variables are merged like ResolveExtends merges them: a template with the same destination, a
+// plugin with the same path or a variable with the same name as a definition of an earlier package replaces that
+// definition in place. Plugins of different packages may share an execution number; they run in the order of the
+// packages then, just like inherited plugins run before the package's own ones. If any of the packages is sandboxed,
+// the composed package is sandboxed too and keeps only the capabilities that all sandboxed packages grant.
+//
+// The returned conflicts describe the templates whose destination was replaced by a later package. Folders that several
+// packages create are not conflicts.
+func Compose(packages []*Package) (*Package, []string) {
+	primary := packages[0]
+	composed := &Package{
+		ID:           primary.ID,
+		CreatedAt:    primary.CreatedAt,
+		UpdatedAt:    primary.UpdatedAt,
+		Name:         primary.Name,
+		Label:        primary.Label,
+		Version:      primary.Version,
+		Description:  primary.Description,
+		ProjectsRoot: primary.ProjectsRoot,
+		Templates:    make([]*Template, 0),
+		Plugins:      make([]*Plugin, 0),
+		Variables:    make([]*Variable, 0),
+		Sandboxed:    primary.Sandboxed,
+		Capabilities: append(StringList(nil), primary.Capabilities...),
+		composition:  make(StringList, 0, len(packages)),
+	}
+
+	conflicts := make([]string, 0)
+	origins := make(map[string]string)
+	for i, pkg := range packages {
+		for _, template := range pkg.Templates {
+			origin, ok := origins[template.Destination]
+			if ok && origin != pkg.Label && !createsFolder(composed.Templates, template) {
+				conflicts = append(conflicts, fmt.Sprintf(
+					"%s of package %s is replaced by package %s", template.Destination, origin, pkg.Label,
+				))
+			}
+			origins[template.Destination] = pkg.Label
+		}
+		composed.Templates = mergeTemplates(composed.Templates, pkg.Templates)
+		composed.Plugins = mergePlugins(composed.Plugins, pkg.Plugins)
+		composed.Variables = mergeVariables(composed.Variables, pkg.Variables)
+		if composed.ProjectsRoot == "" {
+			composed.ProjectsRoot = pkg.ProjectsRoot
+		}
+		if i > 0 {
+			composed.inheritSandbox(pkg)
+		}
+		composed.composition = append(composed.composition, pkg.Label)
+	}
+	return composed, conflicts
+}
+
+// createsFolder reports whether the template and the template with the same destination among templates both create a
+// folder.
+func createsFolder(templates []*Template, template *Template) bool {
+	isFolder := func(t *Template) bool { return !t.IsFile && t.Symlink == "" }
+	for _, existing := range templates {
+		if existing.Destination == template.Destination {
+			return isFolder(existing) && isFolder(template)
+		}
+	}
+	return false
+}
+
+// Labels returns the labels of the packages that were composed into the package in order; see Compose. For packages
+// that were not composed, it only returns the label of the package itself.
+func (c *Package) Labels() StringList {
+	if len(c.composition) == 0 {
+		return StringList{c.Label}
+	}
+	return c.composition
+}
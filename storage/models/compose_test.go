@@ -0,0 +1,202 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompose(t *testing.T) {
+	goPackage := func() *Package {
+		return &Package{
+			ID:    1,
+			Name:  "go",
+			Label: "go",
+			Templates: []*Template{
+				{Destination: "src"},
+				{Destination: "README.md", IsFile: true, Content: "go"},
+				{Destination: "main.go", IsFile: true},
+			},
+			Plugins:   []*Plugin{{Path: "go-mod.lua", ExecNumber: 1}, {Path: "git-init.lua", ExecNumber: 2}},
+			Variables: []*Variable{{Name: "module", Default: "example.com/app"}},
+		}
+	}
+	dockerPackage := func() *Package {
+		return &Package{
+			ID:           2,
+			Name:         "docker",
+			Label:        "dk",
+			ProjectsRoot: "~/services",
+			Templates: []*Template{
+				{Destination: "src"},
+				{Destination: "Dockerfile", IsFile: true},
+			},
+			Plugins:   []*Plugin{{Path: "docker-init.lua", ExecNumber: 1}},
+			Variables: []*Variable{{Name: "port", Default: "8080"}},
+		}
+	}
+	readmePackage := func() *Package {
+		return &Package{
+			ID:        3,
+			Label:     "readme",
+			Templates: []*Template{{Destination: "README.md", IsFile: true, Content: "readme"}},
+			Plugins:   []*Plugin{{Path: "git-init.lua", ExecNumber: 3}},
+			Variables: []*Variable{{Name: "module", Default: "example.com/readme"}},
+		}
+	}
+
+	type result struct {
+		label        string
+		labels       StringList
+		templates    []string // Destinations and the content of inline templates
+		plugins      []string // Post plugins in execution order
+		variables    []string // Names and defaults
+		projectsRoot string
+		conflicts    []string
+	}
+	tests := []struct {
+		name     string
+		packages []*Package
+		want     result
+	}{
+		{
+			name:     "Single package",
+			packages: []*Package{goPackage()},
+			want: result{
+				label:     "go",
+				labels:    StringList{"go"},
+				templates: []string{"src:", "README.md:go", "main.go:"},
+				plugins:   []string{"go-mod.lua", "git-init.lua"},
+				variables: []string{"module:example.com/app"},
+				conflicts: []string{},
+			},
+		},
+		{
+			name:     "Shared folder and execution number",
+			packages: []*Package{goPackage(), dockerPackage()},
+			want: result{
+				label:        "go",
+				labels:       StringList{"go", "dk"},
+				templates:    []string{"src:", "README.md:go", "main.go:", "Dockerfile:"},
+				plugins:      []string{"go-mod.lua", "docker-init.lua", "git-init.lua"},
+				variables:    []string{"module:example.com/app", "port:8080"},
+				projectsRoot: "~/services",
+				conflicts:    []string{},
+			},
+		},
+		{
+			name:     "Order of packages",
+			packages: []*Package{dockerPackage(), goPackage()},
+			want: result{
+				label:        "dk",
+				labels:       StringList{"dk", "go"},
+				templates:    []string{"src:", "Dockerfile:", "README.md:go", "main.go:"},
+				plugins:      []string{"docker-init.lua", "go-mod.lua", "git-init.lua"},
+				variables:    []string{"port:8080", "module:example.com/app"},
+				projectsRoot: "~/services",
+				conflicts:    []string{},
+			},
+		},
+		{
+			name:     "Override",
+			packages: []*Package{goPackage(), readmePackage()},
+			want: result{
+				label:     "go",
+				labels:    StringList{"go", "readme"},
+				templates: []string{"src:", "README.md:readme", "main.go:"},
+				plugins:   []string{"go-mod.lua", "git-init.lua"},
+				variables: []string{"module:example.com/readme"},
+				conflicts: []string{"README.md of package go is replaced by package readme"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		composed, conflicts := Compose(test.packages)
+		assert.Equal(t, test.packages[0].ID, composed.ID, test.name)
+
+		got := result{
+			label:        composed.Label,
+			labels:       composed.Labels(),
+			projectsRoot: composed.ProjectsRoot,
+			conflicts:    conflicts,
+		}
+		for _, template := range composed.Templates {
+			got.templates = append(got.templates, template.Destination+":"+template.Content)
+		}
+		for _, plugin := range composed.PostPlugins() {
+			got.plugins = append(got.plugins, plugin.Path)
+		}
+		for _, variable := range composed.Variables {
+			got.variables = append(got.variables, variable.Name+":"+variable.Default)
+		}
+		assert.Equal(t, test.want, got, test.name)
+	}
+}
+
+func TestComposeSandbox(t *testing.T) {
+	trusted := &Package{Label: "trusted"}
+	sandboxed := &Package{Label: "sandboxed", Sandboxed: true, Capabilities: StringList{"fs", "exec"}}
+	restricted := &Package{Label: "restricted", Sandboxed: true, Capabilities: StringList{"exec", "env"}}
+
+	tests := []struct {
+		name             string
+		packages         []*Package
+		wantSandboxed    bool
+		wantCapabilities StringList
+	}{
+		{name: "Trusted", packages: []*Package{trusted, trusted}, wantCapabilities: StringList{}},
+		{
+			name:             "Sandboxed primary",
+			packages:         []*Package{sandboxed, trusted},
+			wantSandboxed:    true,
+			wantCapabilities: StringList{"fs", "exec"},
+		},
+		{
+			name:             "Sandboxed secondary",
+			packages:         []*Package{trusted, sandboxed},
+			wantSandboxed:    true,
+			wantCapabilities: StringList{"fs", "exec"},
+		},
+		{
+			name:             "Both sandboxed",
+			packages:         []*Package{sandboxed, restricted},
+			wantSandboxed:    true,
+			wantCapabilities: StringList{"exec"},
+		},
+	}
+
+	for _, test := range tests {
+		composed, _ := Compose(test.packages)
+		assert.Equal(t, test.wantSandboxed, composed.Sandboxed, test.name)
+		assert.ElementsMatch(t, test.wantCapabilities, composed.Capabilities, test.name)
+	}
+}
+
+func TestComposedPlan(t *testing.T) {
+	first := &Package{
+		Label:     "first",
+		Templates: []*Template{{Destination: "src"}},
+		Plugins:   []*Plugin{{Path: "b.lua", ExecNumber: -1}, {Path: "c.lua", ExecNumber: 1}},
+	}
+	second := &Package{
+		Label:   "second",
+		Plugins: []*Plugin{{Path: "a.lua", ExecNumber: -1}, {Path: "d.lua", ExecNumber: 1}},
+	}
+
+	composed, _ := Compose([]*Package{first, second})
+	project := NewProject("my-project", "/tmp/my-project", composed)
+	plan, err := project.Plan("/tmp/proji")
+	assert.NoError(t, err)
+
+	got := make([]string, 0, len(plan.Steps))
+	for _, step := range plan.Steps {
+		if step.Plugin != nil {
+			got = append(got, step.Plugin.Path)
+		} else {
+			got = append(got, step.Destination)
+		}
+	}
+	assert.Equal(t, []string{"b.lua", "a.lua", "src", "c.lua", "d.lua"}, got)
+	assert.Equal(t, StringList{"first", "second"}, project.Packages)
+}
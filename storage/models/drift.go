@@ -0,0 +1,108 @@
+package models
+
+import (
+	"path/filepath"
+	"sort"
+)
+
+// Kinds of drift between a project and its package.
+const (
+	DriftMissing  = "missing"  // The package defines the file but it doesn't exist in the project.
+	DriftModified = "modified" // The file was changed in the project since it was generated.
+	DriftOutdated = "outdated" // The package generates the file differently by now.
+	DriftObsolete = "obsolete" // The file was generated but the package doesn't define it anymore.
+)
+
+// Drift is a difference between a file, folder or symlink of a project and its package.
+type Drift struct {
+	Path string `json:"path"` // Path relative to the project folder.
+	Kind string `json:"kind"`
+}
+
+// CheckDrift compares the project on disk with what its package would generate now and with the manifest of what was
+// generated for it. Projects without a manifest are compared with the package only; modified files can't be told apart
+// from outdated ones then and are reported as modified. Existing files that were kept when the project was created are
+// not compared with the package as long as they exist. The variables of the project must be set. Like PrepareUpdate,
+// the package is rendered with the date and user recorded in the manifest.
+func (p *Project) CheckDrift(baseConfigPath string) ([]*Drift, error) {
+	manifest, err := p.readManifest()
+	if err != nil {
+		return nil, err
+	}
+	generated, err := p.generate(baseConfigPath, p.renderData(manifest))
+	if err != nil {
+		return nil, err
+	}
+
+	recorded := make(map[string]*ManifestFile)
+	kept := make(map[string]bool)
+	if manifest != nil {
+		for _, file := range manifest.Files {
+			recorded[filepath.FromSlash(file.Path)] = file
+		}
+		for _, path := range manifest.Kept {
+			kept[filepath.FromSlash(path)] = true
+		}
+	}
+	paths := make([]string, 0, len(generated)+len(recorded))
+	for path := range generated {
+		paths = append(paths, path)
+	}
+	for path := range recorded {
+		if _, ok := generated[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	drift := make([]*Drift, 0)
+	for _, path := range paths {
+		current, err := readEntry(filepath.Join(p.Path, path), nil)
+		if err != nil {
+			return nil, err
+		}
+		if current != nil && kept[path] {
+			// The file existed before the project was created and was kept on purpose
+			continue
+		}
+		kind := driftKind(recorded[path], current, generated[path], manifest != nil)
+		if kind != "" {
+			drift = append(drift, &Drift{Path: filepath.ToSlash(path), Kind: kind})
+		}
+	}
+	return drift, nil
+}
+
+// driftKind returns the kind of drift of a file given its manifest entry, its current version in the project and the
+// version that the package generates now. Each of them may be nil if the file doesn't exist. An empty string is
+// returned if the file didn't drift.
+func driftKind(recorded *ManifestFile, current, generated *snapshotEntry, hasManifest bool) string {
+	switch {
+	case current == nil && generated != nil:
+		return DriftMissing
+	case current == nil:
+		return ""
+	case generated == nil:
+		return DriftObsolete
+	case recorded != nil && !recorded.matches(current):
+		return DriftModified
+	case current.equal(generated) || generated.isDir && current.isDir:
+		return ""
+	case recorded == nil && !hasManifest:
+		return DriftModified
+	default:
+		return DriftOutdated
+	}
+}
+
+// matches reports whether entry is what the manifest file records. Modes are not compared.
+func (f *ManifestFile) matches(entry *snapshotEntry) bool {
+	switch f.Kind {
+	case StepFolder:
+		return entry.isDir
+	case StepSymlink:
+		return entry.isSymlink && f.Target == string(entry.content)
+	default:
+		return !entry.isDir && !entry.isSymlink && f.Hash == hashContent(entry.content)
+	}
+}
@@ -0,0 +1,139 @@
+package models
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nikoksr/proji/render"
+	"github.com/stretchr/testify/assert"
+)
+
+// testPackage returns a package that generates a folder, a file with inline content and a symlink.
+func testPackage() *Package {
+	return &Package{
+		Name:  "python",
+		Label: "py",
+		Templates: []*Template{
+			{Destination: "src"},
+			{Destination: "README.md", IsFile: true, Content: "# {{ .Name }}\n"},
+			{Destination: "docs", Symlink: "README.md"},
+		},
+	}
+}
+
+// createTestProject creates a project of the package inside of a new temporary folder. Files are created before the
+// project and kept if a template would replace them.
+func createTestProject(t *testing.T, pkg *Package, files map[string]string) *Project {
+	tmpDir, err := ioutil.TempDir("", "proji-models")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+
+	project := NewProject("my-project", filepath.Join(tmpDir, "my-project"), pkg)
+	if len(files) > 0 {
+		assert.NoError(t, os.Mkdir(project.Path, os.ModePerm))
+		for path, content := range files {
+			assert.NoError(t, ioutil.WriteFile(filepath.Join(project.Path, path), []byte(content), 0644))
+		}
+		project.AllowExisting = true
+		project.Conflict = func(string, []byte, []byte) (string, error) { return render.ConflictSkip, nil }
+	}
+	assert.NoError(t, project.Create(context.Background(), tmpDir))
+	return project
+}
+
+func TestDriftKind(t *testing.T) {
+	file := &snapshotEntry{content: []byte("a"), mode: 0644}
+	changed := &snapshotEntry{content: []byte("b"), mode: 0644}
+	folder := &snapshotEntry{isDir: true}
+	recorded := newManifestFile("a.txt", file)
+
+	tests := []struct {
+		name        string
+		recorded    *ManifestFile
+		current     *snapshotEntry
+		generated   *snapshotEntry
+		hasManifest bool
+		want        string
+	}{
+		{name: "Unchanged", recorded: recorded, current: file, generated: file, hasManifest: true, want: ""},
+		{name: "Missing", recorded: recorded, generated: file, hasManifest: true, want: DriftMissing},
+		{name: "Removed from package and project", recorded: recorded, hasManifest: true, want: ""},
+		{name: "Obsolete", recorded: recorded, current: file, hasManifest: true, want: DriftObsolete},
+		{name: "Modified", recorded: recorded, current: changed, generated: file, hasManifest: true, want: DriftModified},
+		{name: "Outdated", recorded: recorded, current: file, generated: changed, hasManifest: true, want: DriftOutdated},
+		{name: "Folders", current: folder, generated: folder, hasManifest: true, want: ""},
+		{name: "Not recorded", current: file, generated: changed, hasManifest: true, want: DriftOutdated},
+		{name: "Without manifest", current: file, generated: changed, want: DriftModified},
+	}
+
+	for _, test := range tests {
+		got := driftKind(test.recorded, test.current, test.generated, test.hasManifest)
+		assert.Equal(t, test.want, got, test.name)
+	}
+}
+
+func TestCheckDrift(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing map[string]string
+		change   func(project *Project)
+		want     []*Drift
+	}{
+		{name: "Unchanged", change: func(*Project) {}, want: []*Drift{}},
+		{
+			name: "Modified and missing",
+			change: func(project *Project) {
+				assert.NoError(t, ioutil.WriteFile(filepath.Join(project.Path, "README.md"), []byte("changed"), 0644))
+				assert.NoError(t, os.Remove(filepath.Join(project.Path, "docs")))
+			},
+			want: []*Drift{{Path: "README.md", Kind: DriftModified}, {Path: "docs", Kind: DriftMissing}},
+		},
+		{
+			name: "Outdated and obsolete",
+			change: func(project *Project) {
+				project.Package.Templates[1].Content = "# {{ .Name }} v2\n"
+				project.Package.Templates = project.Package.Templates[:2]
+			},
+			want: []*Drift{{Path: "README.md", Kind: DriftOutdated}, {Path: "docs", Kind: DriftObsolete}},
+		},
+		{
+			name: "Date and user",
+			change: func(project *Project) {
+				project.Package.Templates[1].Content = "# {{ .Name }}\n\n(c) {{ .Date }} {{ .User }}\n"
+				data := project.TemplateData()
+				data.Date = data.Date.AddDate(-1, 0, 0)
+				data.User = "tester"
+				plan, err := project.Plan(filepath.Dir(project.Path))
+				assert.NoError(t, err)
+				assert.NoError(t, project.renderTemplates(project.Path, plan, data))
+				assert.NoError(t, project.writeSnapshot(plan, data, nil))
+			},
+			want: []*Drift{},
+		},
+		{
+			name:     "Kept file",
+			existing: map[string]string{"README.md": "existing"},
+			change:   func(*Project) {},
+			want:     []*Drift{},
+		},
+		{
+			name:     "Kept file removed",
+			existing: map[string]string{"README.md": "existing"},
+			change: func(project *Project) {
+				assert.NoError(t, os.Remove(filepath.Join(project.Path, "README.md")))
+			},
+			want: []*Drift{{Path: "README.md", Kind: DriftMissing}},
+		},
+	}
+
+	for _, test := range tests {
+		project := createTestProject(t, testPackage(), test.existing)
+		test.change(project)
+		got, err := project.CheckDrift(filepath.Dir(project.Path))
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.want, got, test.name)
+	}
+}
@@ -0,0 +1,174 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PackageLoader loads a package by its label without resolving the packages it extends.
+type PackageLoader func(label string) (*Package, error)
+
+// ResolveExtends merges the templates, plugins and variables of the packages that the package extends into it. The
+// extended packages are resolved first, recursively and in the order they are listed; the package's own definitions
+// are applied last. A template with the same destination, a plugin with the same path or a variable with the same name
+// as an earlier definition replaces that definition in place. Inherited definitions remember the label of the package
+// that defined them. Plugins of different packages may share an execution number; the inherited plugins run first
+// then, in the order of the packages.
+//
+// If the projects root is not set, it is inherited as well. A package that extends a sandboxed package becomes
+// sandboxed itself and keeps only the capabilities that all sandboxed packages of the chain grant, so that extending
+// a package never grants its plugins more rights.
+//
+// An error is returned if an extended package can't be loaded, if the packages extend each other in a cycle or if the
+// conditions of the resolved package are invalid.
+func (c *Package) ResolveExtends(load PackageLoader) error {
+	err := c.resolveExtends(load, []string{c.Label})
+	if err != nil {
+		return err
+	}
+	return c.validateConditions()
+}
+
+// resolveExtends resolves the package given the chain of labels of the packages that lead to it.
+func (c *Package) resolveExtends(load PackageLoader, chain []string) error {
+	if len(c.Extends) == 0 {
+		return nil
+	}
+	own := *c
+	c.own = &own
+
+	templates := make([]*Template, 0)
+	plugins := make([]*Plugin, 0)
+	variables := make([]*Variable, 0)
+	for _, label := range c.Extends {
+		for _, seen := range chain {
+			if seen == label {
+				return fmt.Errorf("packages extend each other in a cycle: %s -> %s", strings.Join(chain, " -> "), label)
+			}
+		}
+		parent, err := load(label)
+		if err != nil {
+			return fmt.Errorf("failed to load package %s extended by %s, %s", label, c.Label, err.Error())
+		}
+		err = parent.resolveExtends(load, append(chain[:len(chain):len(chain)], label))
+		if err != nil {
+			return err
+		}
+
+		parent.markInherited()
+		templates = mergeTemplates(templates, parent.Templates)
+		plugins = mergePlugins(plugins, parent.Plugins)
+		variables = mergeVariables(variables, parent.Variables)
+		if c.ProjectsRoot == "" {
+			c.ProjectsRoot = parent.ProjectsRoot
+		}
+		c.inheritSandbox(parent)
+	}
+	c.Templates = mergeTemplates(templates, c.Templates)
+	c.Plugins = mergePlugins(plugins, c.Plugins)
+	c.Variables = mergeVariables(variables, c.Variables)
+	return nil
+}
+
+// markInherited sets the origin of all definitions of the package that are not inherited themselves to the package.
+func (c *Package) markInherited() {
+	for _, template := range c.Templates {
+		if template.Inherited == "" {
+			template.Inherited = c.Label
+		}
+	}
+	for _, plugin := range c.Plugins {
+		if plugin.Inherited == "" {
+			plugin.Inherited = c.Label
+		}
+	}
+	for _, variable := range c.Variables {
+		if variable.Inherited == "" {
+			variable.Inherited = c.Label
+		}
+	}
+}
+
+// inheritSandbox restricts the package to the sandbox of the parent if the parent is sandboxed.
+func (c *Package) inheritSandbox(parent *Package) {
+	if !parent.Sandboxed {
+		return
+	}
+	if !c.Sandboxed {
+		c.Sandboxed = true
+		c.Capabilities = append(StringList(nil), parent.Capabilities...)
+		return
+	}
+	capabilities := make(StringList, 0, len(c.Capabilities))
+	for _, capability := range c.Capabilities {
+		for _, granted := range parent.Capabilities {
+			if capability == granted {
+				capabilities = append(capabilities, capability)
+				break
+			}
+		}
+	}
+	c.Capabilities = capabilities
+}
+
+// mergeTemplates appends the overrides to the templates. Templates with the same destination as an existing template
+// replace it.
+func mergeTemplates(templates, overrides []*Template) []*Template {
+	for _, override := range overrides {
+		replaced := false
+		for i, template := range templates {
+			if template.Destination == override.Destination {
+				templates[i], replaced = override, true
+				break
+			}
+		}
+		if !replaced {
+			templates = append(templates, override)
+		}
+	}
+	return templates
+}
+
+// mergePlugins appends the overrides to the plugins. Plugins with the same path as an existing plugin replace it.
+func mergePlugins(plugins, overrides []*Plugin) []*Plugin {
+	for _, override := range overrides {
+		replaced := false
+		for i, plugin := range plugins {
+			if plugin.Path == override.Path {
+				plugins[i], replaced = override, true
+				break
+			}
+		}
+		if !replaced {
+			plugins = append(plugins, override)
+		}
+	}
+	return plugins
+}
+
+// mergeVariables appends the overrides to the variables. Variables with the same name as an existing variable replace
+// it.
+func mergeVariables(variables, overrides []*Variable) []*Variable {
+	for _, override := range overrides {
+		replaced := false
+		for i, variable := range variables {
+			if variable.Name == override.Name {
+				variables[i], replaced = override, true
+				break
+			}
+		}
+		if !replaced {
+			variables = append(variables, override)
+		}
+	}
+	return variables
+}
+
+// Own returns the package as it was defined, i.e. without the definitions and settings it inherited from the packages
+// it extends.
+func (c *Package) Own() *Package {
+	if c.own == nil {
+		return c
+	}
+	return c.own
+}
@@ -0,0 +1,195 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testLoader returns a package loader that loads fresh copies of the packages returned by the given functions.
+func testLoader(packages map[string]func() *Package) PackageLoader {
+	return func(label string) (*Package, error) {
+		newPackage, ok := packages[label]
+		if !ok {
+			return nil, fmt.Errorf("package %s not found", label)
+		}
+		return newPackage(), nil
+	}
+}
+
+func basePackage() *Package {
+	return &Package{
+		Label:        "base",
+		ProjectsRoot: "~/src",
+		Templates: []*Template{
+			{Destination: "README.md", IsFile: true, Content: "base"},
+			{Destination: "LICENSE", IsFile: true},
+		},
+		Plugins: []*Plugin{
+			{Path: "git-init.lua", ExecNumber: 1},
+			{Path: "hello.lua", ExecNumber: -1},
+		},
+		Variables: []*Variable{{Name: "license", Default: "MIT"}},
+	}
+}
+
+func TestResolveExtends(t *testing.T) {
+	packages := map[string]func() *Package{
+		"base": basePackage,
+		"go": func() *Package {
+			return &Package{
+				Label:     "go",
+				Extends:   StringList{"base"},
+				Templates: []*Template{{Destination: "main.go", IsFile: true}},
+				Plugins:   []*Plugin{{Path: "go-mod.lua", ExecNumber: 1}},
+			}
+		},
+		"cycle-a": func() *Package { return &Package{Label: "cycle-a", Extends: StringList{"cycle-b"}} },
+		"cycle-b": func() *Package { return &Package{Label: "cycle-b", Extends: StringList{"cycle-a"}} },
+	}
+
+	type result struct {
+		templates    []string // Destinations and the packages they were inherited from
+		plugins      []string // Post plugins in execution order and the packages they were inherited from
+		variables    []string
+		projectsRoot string
+	}
+	tests := []struct {
+		name    string
+		pkg     *Package
+		want    result
+		wantErr bool
+	}{
+		{
+			name: "No extends",
+			pkg:  basePackage(),
+			want: result{
+				templates:    []string{"README.md:", "LICENSE:"},
+				plugins:      []string{"git-init.lua:"},
+				variables:    []string{"license:"},
+				projectsRoot: "~/src",
+			},
+		},
+		{
+			name: "Inherit",
+			pkg: &Package{
+				Label:     "py",
+				Extends:   StringList{"base"},
+				Templates: []*Template{{Destination: "main.py", IsFile: true}},
+			},
+			want: result{
+				templates:    []string{"README.md:base", "LICENSE:base", "main.py:"},
+				plugins:      []string{"git-init.lua:base"},
+				variables:    []string{"license:base"},
+				projectsRoot: "~/src",
+			},
+		},
+		{
+			name: "Override",
+			pkg: &Package{
+				Label:        "py",
+				Extends:      StringList{"base"},
+				ProjectsRoot: "~/python",
+				Templates:    []*Template{{Destination: "README.md", IsFile: true, Content: "py"}},
+				Plugins:      []*Plugin{{Path: "git-init.lua", ExecNumber: 2}},
+				Variables:    []*Variable{{Name: "license", Default: "GPL"}},
+			},
+			want: result{
+				templates:    []string{"README.md:", "LICENSE:base"},
+				plugins:      []string{"git-init.lua:"},
+				variables:    []string{"license:"},
+				projectsRoot: "~/python",
+			},
+		},
+		{
+			name: "Shared execution number",
+			pkg: &Package{
+				Label:   "py",
+				Extends: StringList{"base"},
+				Plugins: []*Plugin{{Path: "venv.lua", ExecNumber: 1}},
+			},
+			want: result{
+				templates:    []string{"README.md:base", "LICENSE:base"},
+				plugins:      []string{"git-init.lua:base", "venv.lua:"},
+				variables:    []string{"license:base"},
+				projectsRoot: "~/src",
+			},
+		},
+		{
+			name: "Chain",
+			pkg: &Package{
+				Label:   "web",
+				Extends: StringList{"go"},
+				Plugins: []*Plugin{{Path: "npm.lua", ExecNumber: 1}},
+			},
+			want: result{
+				templates:    []string{"README.md:base", "LICENSE:base", "main.go:go"},
+				plugins:      []string{"git-init.lua:base", "go-mod.lua:go", "npm.lua:"},
+				variables:    []string{"license:base"},
+				projectsRoot: "~/src",
+			},
+		},
+		{name: "Cycle", pkg: &Package{Label: "cycle-a", Extends: StringList{"cycle-b"}}, wantErr: true},
+		{name: "Extends itself", pkg: &Package{Label: "self", Extends: StringList{"self"}}, wantErr: true},
+		{name: "Unknown package", pkg: &Package{Label: "py", Extends: StringList{"unknown"}}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		err := test.pkg.ResolveExtends(testLoader(packages))
+		if test.wantErr {
+			assert.Error(t, err, test.name)
+			continue
+		}
+		assert.NoError(t, err, test.name)
+
+		got := result{projectsRoot: test.pkg.ProjectsRoot}
+		for _, template := range test.pkg.Templates {
+			got.templates = append(got.templates, template.Destination+":"+template.Inherited)
+		}
+		for _, plugin := range test.pkg.PostPlugins() {
+			got.plugins = append(got.plugins, plugin.Path+":"+plugin.Inherited)
+		}
+		for _, variable := range test.pkg.Variables {
+			got.variables = append(got.variables, variable.Name+":"+variable.Inherited)
+		}
+		assert.Equal(t, test.want, got, test.name)
+	}
+}
+
+func TestResolveExtendsSandbox(t *testing.T) {
+	packages := map[string]func() *Package{
+		"base": func() *Package {
+			return &Package{Label: "base", Sandboxed: true, Capabilities: StringList{"fs", "exec"}}
+		},
+	}
+
+	tests := []struct {
+		name             string
+		pkg              *Package
+		wantCapabilities StringList
+	}{
+		{
+			name:             "Trusted",
+			pkg:              &Package{Label: "py", Extends: StringList{"base"}},
+			wantCapabilities: StringList{"fs", "exec"},
+		},
+		{
+			name: "Sandboxed",
+			pkg: &Package{
+				Label:        "py",
+				Extends:      StringList{"base"},
+				Sandboxed:    true,
+				Capabilities: StringList{"exec", "env"},
+			},
+			wantCapabilities: StringList{"exec"},
+		},
+	}
+
+	for _, test := range tests {
+		assert.NoError(t, test.pkg.ResolveExtends(testLoader(packages)), test.name)
+		assert.True(t, test.pkg.Sandboxed, test.name)
+		assert.Equal(t, test.wantCapabilities, test.pkg.Capabilities, test.name)
+		assert.Empty(t, test.pkg.Own().Templates, test.name)
+	}
+}
@@ -0,0 +1,175 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/nikoksr/proji/render"
+	"github.com/pelletier/go-toml"
+)
+
+// Manifest records what proji generated for a project: the packages and variable values it was created with, the files,
+// folders and symlinks the templates produced and the plugins that ran. It is written to the state folder of the
+// project and saved to storage alongside the project.
+type Manifest struct {
+	ID             uint            `gorm:"primarykey" toml:"-"`
+	CreatedAt      time.Time       `toml:"-"`
+	UpdatedAt      time.Time       `toml:"-"`
+	ProjectID      uint            `gorm:"uniqueIndex;not null" toml:"-"`
+	Package        string          `gorm:"not null;size:16" toml:"package"`
+	PackageVersion string          `gorm:"size:32" toml:"package_version,omitempty"` // Version of the primary package; empty if unversioned.
+	Packages       StringList      `gorm:"type:text" toml:"packages,omitempty"`      // Labels of all packages if the project was composed of several.
+	GeneratedAt    time.Time       `toml:"generated_at"`                             // Date that the templates were rendered with.
+	User           string          `gorm:"size:64" toml:"user,omitempty"`            // OS user that the templates were rendered with.
+	Plugins        StringList      `gorm:"type:text" toml:"plugins"`                 // Paths of the plugins that ran, in order.
+	Kept           StringList      `gorm:"type:text" toml:"kept,omitempty"`          // Existing files that were kept instead of being generated.
+	Variables      Options         `gorm:"type:text" toml:"variables"`
+	Files          []*ManifestFile `gorm:"constraint:OnDelete:CASCADE" toml:"file"`
+}
+
+// ManifestFile is a file, folder or symlink that was generated for a project.
+type ManifestFile struct {
+	ID         uint   `gorm:"primarykey" toml:"-"`
+	ManifestID uint   `gorm:"index;not null" toml:"-"`
+	Path       string `gorm:"not null" toml:"path"` // Path relative to the project folder.
+	Kind       string `gorm:"not null;size:16" toml:"kind"`
+	Mode       string `gorm:"size:4" toml:"mode,omitempty"`    // Octal file mode; files only.
+	Hash       string `gorm:"size:64" toml:"sha256,omitempty"` // Hex encoded SHA-256 hash of the content; files only.
+	Target     string `toml:"target,omitempty"`                // Target of the symlink; symlinks only.
+}
+
+// newManifest returns the manifest of the project given the data the templates were rendered with, the files they
+// generated, keyed by their path relative to the project folder, and the paths of the existing files that were kept
+// instead. Kept files are left out of the generated files.
+func (p *Project) newManifest(data *render.Data, files map[string]*snapshotEntry, kept, plugins []string) *Manifest {
+	manifest := &Manifest{
+		Package:        p.Package.Label,
+		PackageVersion: p.Package.Version,
+		Packages:       p.Packages,
+		GeneratedAt:    data.Date.UTC().Truncate(time.Second),
+		User:           data.User,
+		Plugins:        plugins,
+		Kept:           make(StringList, 0, len(kept)),
+		Variables:      Options(p.Variables),
+		Files:          make([]*ManifestFile, 0, len(files)),
+	}
+	if manifest.Plugins == nil {
+		manifest.Plugins = make(StringList, 0)
+	}
+	if manifest.Variables == nil {
+		manifest.Variables = make(Options)
+	}
+
+	isKept := make(map[string]bool, len(kept))
+	for _, path := range kept {
+		isKept[path] = true
+		manifest.Kept = append(manifest.Kept, filepath.ToSlash(path))
+	}
+	sort.Strings(manifest.Kept)
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		if !isKept[path] {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		manifest.Files = append(manifest.Files, newManifestFile(path, files[path]))
+	}
+	return manifest
+}
+
+// newManifestFile returns the manifest entry of a generated file, folder or symlink.
+func newManifestFile(path string, entry *snapshotEntry) *ManifestFile {
+	file := &ManifestFile{Path: filepath.ToSlash(path)}
+	switch {
+	case entry.isDir:
+		file.Kind = StepFolder
+	case entry.isSymlink:
+		file.Kind = StepSymlink
+		file.Target = string(entry.content)
+	default:
+		file.Kind = StepFile
+		file.Mode = fmt.Sprintf("%04o", entry.mode)
+		file.Hash = hashContent(entry.content)
+	}
+	return file
+}
+
+// hashContent returns the hex encoded SHA-256 hash of content.
+func hashContent(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// manifestPath returns the path of the manifest inside of the project folder.
+func manifestPath(projectPath string) string {
+	return filepath.Join(projectPath, StateFolder, manifestFile)
+}
+
+// writeManifest writes the manifest of the project to its state folder.
+func (p *Project) writeManifest() error {
+	path := manifestPath(p.Path)
+	err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return toml.NewEncoder(file).Order(toml.OrderPreserve).Encode(p.Manifest)
+}
+
+// renderData returns the data that the templates of the project were rendered with according to the manifest, so that
+// templates using the date or the user render the same when the package is rendered again. Without a manifest the
+// current date and user are used.
+func (p *Project) renderData(manifest *Manifest) *render.Data {
+	data := p.TemplateData()
+	if manifest == nil {
+		return data
+	}
+	if !manifest.GeneratedAt.IsZero() {
+		data.Date = manifest.GeneratedAt.Local()
+	}
+	if manifest.User != "" {
+		data.User = manifest.User
+	}
+	return data
+}
+
+// readManifest reads the manifest from the state folder of the project. If the project has none, the manifest that was
+// saved to storage is returned, which may be nil.
+func (p *Project) readManifest() (*Manifest, error) {
+	manifest, err := ReadManifest(p.Path)
+	if err != nil || manifest != nil {
+		return manifest, err
+	}
+	return p.Manifest, nil
+}
+
+// ReadManifest reads the manifest from the state folder of the project at projectPath. Projects that were created by
+// older versions of proji have no manifest; nil is returned for them.
+func ReadManifest(projectPath string) (*Manifest, error) {
+	data, err := ioutil.ReadFile(manifestPath(projectPath))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var manifest Manifest
+	err = toml.Unmarshal(data, &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifest, %s", err.Error())
+	}
+	return &manifest, nil
+}
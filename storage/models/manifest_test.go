@@ -0,0 +1,102 @@
+package models
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nikoksr/proji/render"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewManifest(t *testing.T) {
+	project := NewProject("my-project", "/tmp/my-project", &Package{Label: "py", Version: "1.2.0"})
+	project.Variables = render.Vars{"license": "MIT"}
+	files := map[string]*snapshotEntry{
+		"src":                               {isDir: true},
+		filepath.Join("src", "main.py"):     {content: []byte("print('hello')\n"), mode: 0755},
+		"docs":                              {content: []byte("README.md"), isSymlink: true},
+		"README.md":                         {content: []byte("# my-project\n"), mode: 0644},
+		filepath.Join("src", "existing.py"): {content: []byte("kept"), mode: 0644},
+	}
+
+	data := project.TemplateData()
+	data.User = "tester"
+	manifest := project.newManifest(data, files, []string{filepath.Join("src", "existing.py")}, []string{"git-init.lua"})
+	assert.Equal(t, "py", manifest.Package)
+	assert.Equal(t, "1.2.0", manifest.PackageVersion)
+	assert.Equal(t, data.Date.Unix(), manifest.GeneratedAt.Unix())
+	assert.Equal(t, "tester", manifest.User)
+	assert.Empty(t, manifest.Packages)
+	assert.Equal(t, StringList{"git-init.lua"}, manifest.Plugins)
+	assert.Equal(t, StringList{"src/existing.py"}, manifest.Kept)
+	assert.Equal(t, Options{"license": "MIT"}, manifest.Variables)
+	assert.Equal(t, []*ManifestFile{
+		{Path: "README.md", Kind: StepFile, Mode: "0644", Hash: hashContent([]byte("# my-project\n"))},
+		{Path: "docs", Kind: StepSymlink, Target: "README.md"},
+		{Path: "src", Kind: StepFolder},
+		{Path: "src/main.py", Kind: StepFile, Mode: "0755", Hash: hashContent([]byte("print('hello')\n"))},
+	}, manifest.Files)
+}
+
+func TestManifestFileMatches(t *testing.T) {
+	file := newManifestFile("a.txt", &snapshotEntry{content: []byte("a"), mode: 0644})
+	folder := newManifestFile("src", &snapshotEntry{isDir: true})
+	symlink := newManifestFile("docs", &snapshotEntry{content: []byte("README.md"), isSymlink: true})
+
+	tests := []struct {
+		name     string
+		recorded *ManifestFile
+		entry    *snapshotEntry
+		want     bool
+	}{
+		{name: "Same file", recorded: file, entry: &snapshotEntry{content: []byte("a"), mode: 0644}, want: true},
+		{name: "Other mode", recorded: file, entry: &snapshotEntry{content: []byte("a"), mode: 0755}, want: true},
+		{name: "Other content", recorded: file, entry: &snapshotEntry{content: []byte("b"), mode: 0644}},
+		{name: "File replaced by folder", recorded: file, entry: &snapshotEntry{isDir: true}},
+		{name: "File replaced by symlink", recorded: file, entry: &snapshotEntry{content: []byte("a"), isSymlink: true}},
+		{name: "Folder", recorded: folder, entry: &snapshotEntry{isDir: true}, want: true},
+		{name: "Folder replaced by file", recorded: folder, entry: &snapshotEntry{content: []byte("a")}},
+		{
+			name:     "Same symlink",
+			recorded: symlink,
+			entry:    &snapshotEntry{content: []byte("README.md"), isSymlink: true},
+			want:     true,
+		},
+		{name: "Other target", recorded: symlink, entry: &snapshotEntry{content: []byte("LICENSE"), isSymlink: true}},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, test.recorded.matches(test.entry), test.name)
+	}
+}
+
+func TestReadManifest(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "proji-models")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	// Projects without a manifest
+	manifest, err := ReadManifest(tmpDir)
+	assert.NoError(t, err)
+	assert.Nil(t, manifest)
+
+	// Round trip
+	project := NewProject("my-project", tmpDir, &Package{Label: "py"})
+	project.Packages = StringList{"py", "dk"}
+	project.Variables = render.Vars{"license": "MIT"}
+	files := map[string]*snapshotEntry{"README.md": {content: []byte("# my-project\n"), mode: 0644}}
+	project.Manifest = project.newManifest(project.TemplateData(), files, []string{"LICENSE"}, nil)
+	assert.NoError(t, project.writeManifest())
+	manifest, err = ReadManifest(tmpDir)
+	assert.NoError(t, err)
+	assert.Equal(t, project.Manifest.GeneratedAt.Unix(), manifest.GeneratedAt.Unix())
+	manifest.GeneratedAt = project.Manifest.GeneratedAt
+	assert.Equal(t, project.Manifest, manifest)
+
+	// Invalid manifest
+	assert.NoError(t, ioutil.WriteFile(manifestPath(tmpDir), []byte("package = "), 0644))
+	_, err = ReadManifest(tmpDir)
+	assert.Error(t, err)
+}
@@ -8,6 +8,7 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -15,6 +16,9 @@ import (
 
 	gh "github.com/google/go-github/v31/github"
 	"github.com/nikoksr/proji/config"
+	"github.com/nikoksr/proji/expr"
+	"github.com/nikoksr/proji/plugin"
+	"github.com/nikoksr/proji/render"
 	"github.com/nikoksr/proji/repo"
 	"github.com/nikoksr/proji/repo/github"
 	"github.com/nikoksr/proji/repo/gitlab"
@@ -27,16 +31,26 @@ import (
 // Package represents a proji package; the central item of proji's project creation mechanism. It holds tags for gorm and
 // toml defining its storage and export/import behaviour.
 type Package struct {
-	ID          uint           `gorm:"primarykey" toml:"-"`
-	CreatedAt   time.Time      `toml:"-"`
-	UpdatedAt   time.Time      `toml:"-"`
-	DeletedAt   gorm.DeletedAt `gorm:"index:idx_unq_package_label_deletedat,unique;" toml:"-"`
-	Name        string         `gorm:"not null;size:64" toml:"name"`
-	Label       string         `gorm:"index:idx_unq_package_label_deletedat,unique;not null;size:16" toml:"label"`
-	Description string         `gorm:"size:255" toml:"description"`
-	Templates   []*Template    `gorm:"many2many:package_templates;ForeignKey:ID;References:ID" toml:"template"`
-	Plugins     []*Plugin      `gorm:"many2many:package_plugins;ForeignKey:ID;References:ID" toml:"plugin"`
-	IsDefault   bool           `gorm:"not null" toml:"-"`
+	ID           uint           `gorm:"primarykey" toml:"-"`
+	CreatedAt    time.Time      `toml:"-"`
+	UpdatedAt    time.Time      `toml:"-"`
+	DeletedAt    gorm.DeletedAt `gorm:"index:idx_unq_package_label_version_deletedat,unique;" toml:"-"`
+	Name         string         `gorm:"not null;size:64" toml:"name"`
+	Label        string         `gorm:"index:idx_unq_package_label_version_deletedat,unique;not null;size:16" toml:"label"`
+	Version      string         `gorm:"index:idx_unq_package_label_version_deletedat,unique;not null;default:'';size:32" toml:"version,omitempty"` // Semantic version like 1.2.0; may be empty.
+	Description  string         `gorm:"size:255" toml:"description"`
+	Capabilities StringList     `gorm:"type:text" toml:"capabilities,omitempty"`
+	ProjectsRoot string         `gorm:"size:255" toml:"projects_root,omitempty"`
+	Extends      StringList     `gorm:"type:text" toml:"extends,omitempty"` // Labels of the packages this one extends.
+	Templates    []*Template    `gorm:"many2many:package_templates;ForeignKey:ID;References:ID" toml:"template"`
+	Plugins      []*Plugin      `gorm:"many2many:package_plugins;ForeignKey:ID;References:ID" toml:"plugin"`
+	Variables    []*Variable    `gorm:"many2many:package_variables;ForeignKey:ID;References:ID" toml:"variable"`
+	Sandboxed    bool           `gorm:"not null;default:false" toml:"-"`
+	IsDefault    bool           `gorm:"not null" toml:"-"`
+	Archived     bool           `gorm:"not null;default:false" toml:"-"` // Whether a newer version of the package replaced it.
+
+	own         *Package   // The package as it was defined, before ResolveExtends merged the extended packages into it.
+	composition StringList // Labels of the packages that Compose layered into the package.
 }
 
 const (
@@ -44,6 +58,11 @@ const (
 	pluginsKey   = "plugins"   // Map key for plugins.
 )
 
+// semanticVersion matches semantic versions as defined by https://semver.org.
+var semanticVersion = regexp.MustCompile(
+	`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(-[0-9A-Za-z-]+(\.[0-9A-Za-z-]+)*)?(\+[0-9A-Za-z-]+(\.[0-9A-Za-z-]+)*)?$`,
+)
+
 // NewPackage returns a new package instance. isDefault should be false by default and only true for fallback packages
 // that should be ignored anyways.
 func NewPackage(name, label string, isDefault bool) *Package {
@@ -92,7 +111,7 @@ func (c *Package) ImportFromConfig(path string) error {
 	if c.isEmpty() {
 		return fmt.Errorf("no relevant data was found. Config might be empty")
 	}
-	return nil
+	return c.validate()
 }
 
 // ImportFromFolderStructure imports a package from a given directory. Proji will imitate the
@@ -122,15 +141,24 @@ func (c *Package) ImportFromFolderStructure(path string, excludeDirs []string) e
 			return err
 		}
 
-		// Add file or folder to package
-		isFile := true
-		if info.IsDir() {
+		// Add file, folder or symlink to package
+		template := &Template{IsFile: true, Path: "", Destination: relPath}
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			template.Symlink, err = os.Readlink(currentPath)
+			if err != nil {
+				return err
+			}
+		case info.IsDir():
 			if util.IsInSlice(excludeDirs, info.Name()) {
 				return filepath.SkipDir
 			}
-			isFile = false
+			template.IsFile = false
+		case info.Mode()&0111 != 0:
+			// Keep executable bits of files like scripts
+			template.Mode = fmt.Sprintf("%04o", info.Mode().Perm())
 		}
-		c.Templates = append(c.Templates, &Template{IsFile: isFile, Path: "", Destination: relPath})
+		c.Templates = append(c.Templates, template)
 		return nil
 	})
 
@@ -222,6 +250,10 @@ func (c *Package) ImportFromRepo(packageURL *url.URL, importer repo.Importer) er
 		}
 	}
 	for _, template := range c.Templates {
+		// Templates with inline content or without template have nothing to download
+		if template.Path == "" {
+			continue
+		}
 		filesToDownload[templatesKey] = append(filesToDownload[templatesKey], template.Path)
 	}
 	for _, plugin := range c.Plugins {
@@ -271,6 +303,7 @@ func (c *Package) ImportFromRepo(packageURL *url.URL, importer repo.Importer) er
 // ImportCollectionFromRepo imports all packages from a given URL. A collection is a repo with multiple packages. It must include
 // a folder called configs, which holds the package configs. If the packages have plugins or templates as dependencies,
 // they should be put into the folders plugins/ and templates/ respectively.
+//
 //nolint:interfacer
 func ImportCollectionFromRepo(collectionURL *url.URL, importer repo.Importer) ([]*Package, error) {
 	// Get list of package configs and loop through them
@@ -338,7 +371,8 @@ func ImportCollectionFromRepo(collectionURL *url.URL, importer repo.Importer) ([
 	return packageList, err
 }
 
-// ExportConfig exports a given package to a toml config file.
+// ExportConfig exports a given package to a toml config file. Definitions that the package inherited from the packages
+// it extends are not exported; the config extends the same packages instead.
 func (c *Package) ExportConfig(destination string) (string, error) {
 	confName := filepath.Join(destination, "proji-"+c.Name+".toml")
 	conf, err := os.Create(confName)
@@ -346,12 +380,294 @@ func (c *Package) ExportConfig(destination string) (string, error) {
 		return confName, err
 	}
 	defer conf.Close()
-	return confName, toml.NewEncoder(conf).Order(toml.OrderPreserve).Encode(c)
+	return confName, toml.NewEncoder(conf).Order(toml.OrderPreserve).Encode(c.Own())
+}
+
+// validate validates the templates, variable definitions and conditions of the package.
+func (c *Package) validate() error {
+	for _, template := range c.Templates {
+		err := template.Validate()
+		if err != nil {
+			return err
+		}
+	}
+	for _, plugin := range c.Plugins {
+		err := plugin.Validate()
+		if err != nil {
+			return err
+		}
+	}
+	err := c.ValidatePluginOrder()
+	if err != nil {
+		return err
+	}
+	err = c.validateVariables()
+	if err != nil {
+		return err
+	}
+	err = c.validateCapabilities()
+	if err != nil {
+		return err
+	}
+	err = c.validateProjectsRoot()
+	if err != nil {
+		return err
+	}
+	err = c.validateVersion()
+	if err != nil {
+		return err
+	}
+	err = c.validateExtends()
+	if err != nil {
+		return err
+	}
+	return c.validateConditions()
+}
+
+// ValidatePluginOrder makes sure that every plugin has a non-zero execution number and that no execution number is used
+// more than once, so that the execution order of the plugins is unambiguous. It applies to the package's own plugins;
+// plugins of different packages may share an execution number, see ResolveExtends and Compose.
+func (c *Package) ValidatePluginOrder() error {
+	paths := make(map[int]string, len(c.Plugins))
+	for _, plugin := range c.Plugins {
+		if plugin.ExecNumber == 0 {
+			return fmt.Errorf("plugin %s has no execution number, it must be negative or positive", plugin.Path)
+		}
+		if other, ok := paths[plugin.ExecNumber]; ok {
+			return fmt.Errorf(
+				"plugins %s and %s share the execution number %d",
+				other,
+				plugin.Path,
+				plugin.ExecNumber,
+			)
+		}
+		paths[plugin.ExecNumber] = plugin.Path
+	}
+	return nil
+}
+
+// PrePlugins returns the plugins that run before the templates get created; all plugins with a negative execution
+// number sorted in ascending order. Plugins with the same execution number keep their order.
+func (c *Package) PrePlugins() []*Plugin {
+	return c.sortedPlugins(func(execNumber int) bool { return execNumber < 0 })
+}
+
+// PostPlugins returns the plugins that run after the templates were created; all plugins with a positive execution
+// number sorted in ascending order. Plugins with the same execution number keep their order.
+func (c *Package) PostPlugins() []*Plugin {
+	return c.sortedPlugins(func(execNumber int) bool { return execNumber > 0 })
+}
+
+// sortedPlugins returns the plugins whose execution number matches the filter sorted by their execution number.
+func (c *Package) sortedPlugins(filter func(execNumber int) bool) []*Plugin {
+	plugins := make([]*Plugin, 0, len(c.Plugins))
+	for _, plugin := range c.Plugins {
+		if filter(plugin.ExecNumber) {
+			plugins = append(plugins, plugin)
+		}
+	}
+	sort.SliceStable(plugins, func(i, j int) bool {
+		return plugins[i].ExecNumber < plugins[j].ExecNumber
+	})
+	return plugins
+}
+
+// validateCapabilities makes sure that the package only declares known plugin capabilities.
+func (c *Package) validateCapabilities() error {
+	for _, capability := range c.Capabilities {
+		if !plugin.IsCapability(capability) {
+			return fmt.Errorf(
+				"unknown capability '%s', valid capabilities are %s",
+				capability,
+				strings.Join(plugin.Capabilities(), ", "),
+			)
+		}
+	}
+	return nil
+}
+
+// validateProjectsRoot makes sure that the projects root is a valid path template.
+func (c *Package) validateProjectsRoot() error {
+	err := render.Validate("projects_root", c.ProjectsRoot)
+	if err != nil {
+		return fmt.Errorf("invalid projects root '%s', %s", c.ProjectsRoot, err.Error())
+	}
+	return nil
+}
+
+// validateVersion makes sure that the version of the package is empty or a semantic version like 1.2.0.
+func (c *Package) validateVersion() error {
+	if c.Version != "" && !semanticVersion.MatchString(c.Version) {
+		return fmt.Errorf("version '%s' is not a semantic version like 1.2.0", c.Version)
+	}
+	return nil
+}
+
+// CompareVersions compares two semantic versions by their precedence as defined by https://semver.org. It returns -1
+// if a is lower than b, 1 if a is higher than b and 0 if both are equal. Build metadata is ignored and an empty version
+// is lower than any other version. Both versions have to be valid.
+func CompareVersions(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return -1
+	case b == "":
+		return 1
+	}
+	a, b = strings.SplitN(a, "+", 2)[0], strings.SplitN(b, "+", 2)[0]
+	aRelease, aPre := splitPrerelease(a)
+	bRelease, bPre := splitPrerelease(b)
+
+	aParts, bParts := strings.Split(aRelease, "."), strings.Split(bRelease, ".")
+	for i := range aParts {
+		if c := compareIdentifiers(aParts[i], bParts[i]); c != 0 {
+			return c
+		}
+	}
+
+	// A pre-release has a lower precedence than its release
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+	aParts, bParts = strings.Split(aPre, "."), strings.Split(bPre, ".")
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		if c := compareIdentifiers(aParts[i], bParts[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInts(len(aParts), len(bParts))
+}
+
+// splitPrerelease splits a version without build metadata into its release and pre-release part; e.g. 1.2.0-rc.1
+// into 1.2.0 and rc.1.
+func splitPrerelease(version string) (string, string) {
+	parts := strings.SplitN(version, "-", 2)
+	if len(parts) < 2 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// compareIdentifiers compares two identifiers of a version. Numeric identifiers are compared numerically and have a
+// lower precedence than alphanumeric ones, which are compared lexically.
+func compareIdentifiers(a, b string) int {
+	aNumeric, bNumeric := isNumeric(a), isNumeric(b)
+	switch {
+	case aNumeric && bNumeric:
+		// Numeric identifiers have no leading zeros; the longer one is the bigger number
+		if c := compareInts(len(a), len(b)); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	case aNumeric:
+		return -1
+	case bNumeric:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+// isNumeric reports whether s consists of digits only.
+func isNumeric(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// compareInts returns -1 if a is lower than b, 1 if a is higher than b and 0 if both are equal.
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// validateExtends makes sure that the package extends every package only once and not itself.
+func (c *Package) validateExtends() error {
+	labels := make(map[string]bool, len(c.Extends))
+	for _, label := range c.Extends {
+		switch {
+		case strings.TrimSpace(label) == "":
+			return fmt.Errorf("extends may not contain empty labels")
+		case label == c.Label:
+			return fmt.Errorf("package %s may not extend itself", c.Label)
+		case labels[label]:
+			return fmt.Errorf("package %s is extended more than once", label)
+		}
+		labels[label] = true
+	}
+	return nil
+}
+
+// validateConditions makes sure that the conditions of all templates and plugins are valid expressions which only
+// reference variables that are defined by the package. Conditions of packages that extend others may reference the
+// variables of the extended packages, so those are only checked once the package was resolved.
+func (c *Package) validateConditions() error {
+	checkVariables := len(c.Extends) == 0 || c.own != nil
+	variables := make(map[string]bool, len(c.Variables))
+	for _, variable := range c.Variables {
+		variables[variable.Name] = true
+	}
+
+	conditions := make([]string, 0)
+	for _, template := range c.Templates {
+		conditions = append(conditions, template.When)
+	}
+	for _, plugin := range c.Plugins {
+		conditions = append(conditions, plugin.When)
+	}
+
+	for _, condition := range conditions {
+		if condition == "" {
+			continue
+		}
+		expression, err := expr.Parse(condition)
+		if err != nil {
+			return fmt.Errorf("invalid condition '%s', %s", condition, err.Error())
+		}
+		for _, name := range expression.Identifiers() {
+			if checkVariables && !variables[name] {
+				return fmt.Errorf("condition '%s' references undefined variable '%s'", condition, name)
+			}
+		}
+	}
+	return nil
+}
+
+// validateVariables validates the variable definitions of the package and makes sure that no variable name is used
+// twice.
+func (c *Package) validateVariables() error {
+	names := make(map[string]bool, len(c.Variables))
+	for _, variable := range c.Variables {
+		err := variable.Validate()
+		if err != nil {
+			return err
+		}
+		if names[variable.Name] {
+			return fmt.Errorf("variable '%s' is defined more than once", variable.Name)
+		}
+		names[variable.Name] = true
+	}
+	return nil
 }
 
-// isEmpty checks if the package holds no data.
+// isEmpty checks if the package holds no data. Packages that extend others are never empty.
 func (c *Package) isEmpty() bool {
-	if len(c.Templates) == 0 && len(c.Plugins) == 0 {
+	if len(c.Templates) == 0 && len(c.Plugins) == 0 && len(c.Extends) == 0 {
 		return true
 	}
 	return false
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want int
+	}{
+		{name: "Equal", a: "1.2.0", b: "1.2.0", want: 0},
+		{name: "Both empty", a: "", b: "", want: 0},
+		{name: "Empty", a: "", b: "0.0.1", want: -1},
+		{name: "Major", a: "2.0.0", b: "1.9.9", want: 1},
+		{name: "Minor", a: "1.2.0", b: "1.10.0", want: -1},
+		{name: "Patch", a: "1.2.10", b: "1.2.9", want: 1},
+		{name: "Pre-release", a: "1.2.0-rc.1", b: "1.2.0", want: -1},
+		{name: "Pre-release of newer version", a: "1.3.0-rc.1", b: "1.2.0", want: 1},
+		{name: "Numeric pre-release", a: "1.2.0-rc.2", b: "1.2.0-rc.10", want: -1},
+		{name: "Alphanumeric pre-release", a: "1.2.0-beta", b: "1.2.0-alpha", want: 1},
+		{name: "Numeric and alphanumeric pre-release", a: "1.2.0-1", b: "1.2.0-alpha", want: -1},
+		{name: "Longer pre-release", a: "1.2.0-alpha.1", b: "1.2.0-alpha", want: 1},
+		{name: "Build metadata", a: "1.2.0+build.2", b: "1.2.0+build.1", want: 0},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, CompareVersions(test.a, test.b), test.name)
+		assert.Equal(t, -test.want, CompareVersions(test.b, test.a), test.name)
+	}
+}
@@ -0,0 +1,144 @@
+package models
+
+import (
+	"path/filepath"
+
+	"github.com/nikoksr/proji/render"
+)
+
+// Kinds of the steps of a project creation.
+const (
+	StepPlugin  = "plugin"
+	StepFolder  = "folder"
+	StepFile    = "file"
+	StepSymlink = "symlink"
+)
+
+// Step is a single step of a project creation; either the creation of a file, folder or symlink from a template or the
+// execution of a plugin.
+type Step struct {
+	Kind        string
+	Template    *Template // Template the step creates; nil for plugin steps.
+	Plugin      *Plugin   // Plugin the step runs; nil for template steps.
+	Destination string    // Destination relative to the project folder with resolved placeholders; template steps only.
+	Source      string    // Absolute path of the template or plugin, or the target of a symlink. Empty if not needed.
+	Args        []string  // Plugin arguments with resolved placeholders; plugin steps only.
+	Skipped     bool      // Whether the condition is not met. Skipped steps are not resolved any further.
+}
+
+// Plan is the ordered list of steps that the creation of a project consists of. Pre plugins come first, then the
+// templates and the post plugins last.
+type Plan struct {
+	Steps []*Step
+}
+
+// Plan resolves the steps that creating the project would take without touching the disk or storage. Conditions,
+// destinations, symlink targets and plugin arguments are resolved with the project variables.
+func (p *Project) Plan(baseConfigPath string) (*Plan, error) {
+	data := p.TemplateData()
+	pluginsPath := filepath.Join(baseConfigPath, "plugins")
+	templatesPath := filepath.Join(baseConfigPath, "templates")
+	plan := &Plan{Steps: make([]*Step, 0, len(p.Package.Templates)+len(p.Package.Plugins))}
+
+	addPlugins := func(plugins []*Plugin) error {
+		for _, plugin := range plugins {
+			step, err := p.pluginStep(plugin, pluginsPath, data)
+			if err != nil {
+				return err
+			}
+			plan.Steps = append(plan.Steps, step)
+		}
+		return nil
+	}
+
+	err := addPlugins(p.Package.PrePlugins())
+	if err != nil {
+		return nil, err
+	}
+	for _, template := range p.Package.Templates {
+		step, err := p.templateStep(template, templatesPath, data)
+		if err != nil {
+			return nil, err
+		}
+		plan.Steps = append(plan.Steps, step)
+	}
+	err = addPlugins(p.Package.PostPlugins())
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// pluginStep returns the step that runs the given plugin.
+func (p *Project) pluginStep(plugin *Plugin, pluginsPath string, data *render.Data) (*Step, error) {
+	met, err := p.conditionMet(plugin.When)
+	if err != nil {
+		return nil, err
+	}
+	if !met {
+		return &Step{Kind: StepPlugin, Plugin: plugin, Skipped: true}, nil
+	}
+	source, err := plugin.AbsolutePath(pluginsPath)
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, 0, len(plugin.Args))
+	for _, arg := range plugin.Args {
+		resolved, err := render.Path(arg, data)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, resolved)
+	}
+	return &Step{
+		Kind:   StepPlugin,
+		Plugin: plugin,
+		Source: source,
+		Args:   args,
+	}, nil
+}
+
+// templateStep returns the step that creates the file, folder or symlink of the given template.
+func (p *Project) templateStep(template *Template, templatesPath string, data *render.Data) (*Step, error) {
+	step := &Step{Template: template, Destination: template.Destination}
+	switch {
+	case len(template.Symlink) > 0:
+		step.Kind = StepSymlink
+	case template.IsFile:
+		step.Kind = StepFile
+	default:
+		step.Kind = StepFolder
+	}
+
+	met, err := p.conditionMet(template.When)
+	if err != nil {
+		return nil, err
+	}
+	if !met {
+		step.Skipped = true
+		return step, nil
+	}
+
+	step.Destination, err = render.Path(template.Destination, data)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case step.Kind == StepSymlink:
+		step.Source, err = render.Path(template.Symlink, data)
+		if err != nil {
+			return nil, err
+		}
+	case len(template.Path) > 0:
+		step.Source = filepath.Join(templatesPath, template.Path)
+	}
+	return step, nil
+}
+
+// Condition returns the condition of the template or plugin of the step.
+func (s *Step) Condition() string {
+	if s.Plugin != nil {
+		return s.Plugin.When
+	}
+	return s.Template.When
+}
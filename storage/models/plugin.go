@@ -1,9 +1,14 @@
 package models
 
 import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
 	"time"
 
-	lua "github.com/yuin/gopher-lua"
+	"github.com/nikoksr/proji/plugin"
+	"github.com/nikoksr/proji/render"
 	"gorm.io/gorm"
 )
 
@@ -14,13 +19,135 @@ type Plugin struct {
 	CreatedAt   time.Time      `toml:"-"`
 	UpdatedAt   time.Time      `toml:"-"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" toml:"-"`
-	Path        string         `gorm:"index:idx_plugin_path,unique;not null" toml:"path"`
+	Path        string         `gorm:"index;not null" toml:"path"`
+	Type        string         `gorm:"size:8" toml:"type,omitempty"` // lua, shell or exec; inferred from the path if empty.
 	ExecNumber  int            `gorm:"check:(exec_number != 0);not null;size:4" toml:"exec_number"`
 	Description string         `gorm:"size:255" toml:"description"`
+	Args        StringList     `gorm:"type:text" toml:"args,omitempty"`
+	When        string         `gorm:"size:255" toml:"when,omitempty"`
+	Timeout     string         `gorm:"size:32" toml:"timeout,omitempty"` // Duration like "30s"; overrides the default.
+	OnFailure   string         `gorm:"size:8" toml:"on_failure,omitempty"`
+	Options     Options        `gorm:"type:text" toml:"options,omitempty"` // Keep last, a toml table swallows the keys after it.
+	Inherited   string         `gorm:"-" toml:"-"`                         // Label of the package it was inherited from; see ResolveExtends.
 }
 
-func (p *Plugin) Run() error {
-	L := lua.NewState()
-	defer L.Close()
-	return L.DoFile(p.Path)
+// Policies that determine how the project creation proceeds if a plugin fails.
+const (
+	FailureAbort    = "abort"    // Abort the project creation. The default.
+	FailureContinue = "continue" // Ignore the failure and continue.
+	FailureWarn     = "warn"     // Report the failure as a warning and continue.
+)
+
+// FailurePolicy returns the failure policy of the plugin. Defaults to FailureAbort.
+func (p *Plugin) FailurePolicy() string {
+	if p.OnFailure == "" {
+		return FailureAbort
+	}
+	return p.OnFailure
+}
+
+// Validate makes sure that the plugin has a path inside of the plugins folder, a known type, a known failure policy and
+// a valid timeout.
+func (p *Plugin) Validate() error {
+	err := p.validatePath()
+	if err != nil {
+		return err
+	}
+	if p.Type != "" && !plugin.IsType(p.Type) {
+		return fmt.Errorf(
+			"invalid type '%s' of plugin %s, valid types are %s",
+			p.Type,
+			p.Path,
+			strings.Join(plugin.Types(), ", "),
+		)
+	}
+	switch p.FailurePolicy() {
+	case FailureAbort, FailureContinue, FailureWarn:
+	default:
+		return fmt.Errorf(
+			"invalid on_failure policy '%s' of plugin %s, valid policies are %s, %s and %s",
+			p.OnFailure,
+			p.Path,
+			FailureAbort,
+			FailureContinue,
+			FailureWarn,
+		)
+	}
+	_, err = p.TimeoutDuration()
+	return err
+}
+
+// validatePath makes sure that the plugin path is a relative path that doesn't lead outside of the plugins folder.
+func (p *Plugin) validatePath() error {
+	if p.Path == "" {
+		return fmt.Errorf("plugin path must not be empty")
+	}
+	path := filepath.Clean(p.Path)
+	if filepath.IsAbs(path) || path == "." || path == ".." || strings.HasPrefix(path, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("plugin path %s must be a path inside of the plugins folder", p.Path)
+	}
+	return nil
+}
+
+// ResolvedType returns the type of the plugin. If the plugin has no explicit type, it is inferred from the file
+// extension of its path.
+func (p *Plugin) ResolvedType() string {
+	if p.Type != "" {
+		return p.Type
+	}
+	return plugin.TypeOf(p.Path)
+}
+
+// TimeoutDuration returns the parsed timeout of the plugin. Returns zero if the plugin has no timeout of its own.
+func (p *Plugin) TimeoutDuration() (time.Duration, error) {
+	if p.Timeout == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(p.Timeout)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("invalid timeout '%s' of plugin %s, expected a positive duration like '30s'", p.Timeout, p.Path)
+	}
+	return timeout, nil
+}
+
+// Run executes the plugin for the project described by the given environment. Placeholders like __PROJECT_NAME__
+// in the plugin arguments are resolved before they are passed to the plugin. The timeout of the plugin takes precedence
+// over the default timeout of the environment.
+func (p *Plugin) Run(ctx context.Context, env *plugin.Env) error {
+	timeout, err := p.TimeoutDuration()
+	if err != nil {
+		return err
+	}
+
+	args := make([]string, 0, len(p.Args))
+	for _, arg := range p.Args {
+		resolved, err := render.Path(arg, env.Data)
+		if err != nil {
+			return err
+		}
+		args = append(args, resolved)
+	}
+
+	path, err := p.AbsolutePath(env.PluginsPath)
+	if err != nil {
+		return err
+	}
+
+	pluginEnv := *env
+	pluginEnv.Args = args
+	pluginEnv.Options = p.Options
+	if timeout > 0 {
+		pluginEnv.Timeout = timeout
+	}
+	return plugin.Run(ctx, p.ResolvedType(), path, &pluginEnv)
+}
+
+// AbsolutePath returns the absolute path of the plugin. Plugin paths are relative to proji's plugins folder to make
+// them shareable; paths that lead outside of it are rejected.
+func (p *Plugin) AbsolutePath(pluginsPath string) (string, error) {
+	err := p.validatePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(pluginsPath, p.Path), nil
 }
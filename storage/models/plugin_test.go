@@ -0,0 +1,112 @@
+package models
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPluginValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		plugin  *Plugin
+		wantErr bool
+	}{
+		{name: "Lua plugin", plugin: &Plugin{Path: "git-init.lua", ExecNumber: 1}},
+		{name: "Nested path", plugin: &Plugin{Path: "git/init.sh", ExecNumber: 1, OnFailure: FailureWarn}},
+		{name: "Explicit type", plugin: &Plugin{Path: "init", Type: "exec", ExecNumber: 1, Timeout: "30s"}},
+		{name: "Inner parent folder", plugin: &Plugin{Path: "git/../init.lua", ExecNumber: 1}},
+		{name: "Empty path", plugin: &Plugin{ExecNumber: 1}, wantErr: true},
+		{name: "Absolute path", plugin: &Plugin{Path: "/usr/bin/env", ExecNumber: 1}, wantErr: true},
+		{name: "Parent folder", plugin: &Plugin{Path: "../templates/run.sh", ExecNumber: 1}, wantErr: true},
+		{name: "Escaping path", plugin: &Plugin{Path: "git/../../run.sh", ExecNumber: 1}, wantErr: true},
+		{name: "Plugins folder", plugin: &Plugin{Path: ".", ExecNumber: 1}, wantErr: true},
+		{name: "Unknown type", plugin: &Plugin{Path: "init.py", Type: "python", ExecNumber: 1}, wantErr: true},
+		{name: "Unknown policy", plugin: &Plugin{Path: "init.lua", ExecNumber: 1, OnFailure: "retry"}, wantErr: true},
+		{name: "Invalid timeout", plugin: &Plugin{Path: "init.lua", ExecNumber: 1, Timeout: "-1s"}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		err := test.plugin.Validate()
+		if test.wantErr {
+			assert.Error(t, err, test.name)
+			continue
+		}
+		assert.NoError(t, err, test.name)
+	}
+}
+
+func TestPluginAbsolutePath(t *testing.T) {
+	pluginsPath := filepath.Join("/home", "user", ".config", "proji", "plugins")
+
+	path, err := (&Plugin{Path: "git/init.lua"}).AbsolutePath(pluginsPath)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(pluginsPath, "git", "init.lua"), path)
+
+	for _, invalid := range []string{"/usr/bin/env", "../proji.sqlite3", "git/../../init.lua"} {
+		_, err = (&Plugin{Path: invalid}).AbsolutePath(pluginsPath)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestValidatePluginOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugins  []*Plugin
+		wantPre  []string
+		wantPost []string
+		wantErr  bool
+	}{
+		{name: "No plugins"},
+		{
+			name: "Pre and post plugins",
+			plugins: []*Plugin{
+				{Path: "c.lua", ExecNumber: 2},
+				{Path: "a.lua", ExecNumber: -1},
+				{Path: "d.lua", ExecNumber: 10},
+				{Path: "b.lua", ExecNumber: -5},
+				{Path: "e.lua", ExecNumber: 1},
+			},
+			wantPre:  []string{"b.lua", "a.lua"},
+			wantPost: []string{"e.lua", "c.lua", "d.lua"},
+		},
+		{
+			name:     "Same plugin twice",
+			plugins:  []*Plugin{{Path: "a.lua", ExecNumber: 1}, {Path: "a.lua", ExecNumber: 2}},
+			wantPost: []string{"a.lua", "a.lua"},
+		},
+		{
+			name:    "Duplicate execution number",
+			plugins: []*Plugin{{Path: "a.lua", ExecNumber: 1}, {Path: "b.lua", ExecNumber: 1}},
+			wantErr: true,
+		},
+		{
+			name:    "Duplicate negative execution number",
+			plugins: []*Plugin{{Path: "a.lua", ExecNumber: -2}, {Path: "b.lua", ExecNumber: 1}, {Path: "c.lua", ExecNumber: -2}},
+			wantErr: true,
+		},
+		{name: "Missing execution number", plugins: []*Plugin{{Path: "a.lua"}}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		pkg := &Package{Label: "py", Plugins: test.plugins}
+		err := pkg.ValidatePluginOrder()
+		if test.wantErr {
+			assert.Error(t, err, test.name)
+			continue
+		}
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.wantPre, pluginPaths(pkg.PrePlugins()), test.name)
+		assert.Equal(t, test.wantPost, pluginPaths(pkg.PostPlugins()), test.name)
+	}
+}
+
+// pluginPaths returns the paths of the plugins, or nil if there are none.
+func pluginPaths(plugins []*Plugin) []string {
+	var paths []string
+	for _, plugin := range plugins {
+		paths = append(paths, plugin.Path)
+	}
+	return paths
+}
@@ -1,11 +1,16 @@
 package models
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
-	"github.com/otiai10/copy"
+	"github.com/nikoksr/proji/expr"
+	"github.com/nikoksr/proji/plugin"
+	"github.com/nikoksr/proji/render"
 	"gorm.io/gorm"
 )
 
@@ -18,119 +23,266 @@ type Project struct {
 	DeletedAt gorm.DeletedAt `gorm:"index"`
 	Name      string         `gorm:"size:64"`
 	Path      string         `gorm:"index:idx_unq_project_path_deletedat,unique;not null"`
-	Package   *Package       `gorm:"ForeignKey:ID;References:ID"`
+	PackageID uint           `gorm:"index"`
+	Package   *Package       `gorm:"ForeignKey:PackageID;constraint:-"` // No constraint; packages may be removed.
+	Packages  StringList     `gorm:"type:text"`                         // Labels of all packages if it was composed of several.
+	Manifest  *Manifest      `gorm:"constraint:OnDelete:CASCADE"`       // What was generated; set by Create.
+	Variables render.Vars    `gorm:"-"`
+	Skipped   []string       `gorm:"-"` // Templates, plugins and existing files that were skipped.
+	Warnings  []string       `gorm:"-"` // Failures of plugins whose failure policy is to warn.
+	Backups   []string       `gorm:"-"` // Existing files that were renamed to make room for templates.
+	Conflicts []string       `gorm:"-"` // Templates that a later package of the composition replaces; see Compose.
+	Missing   []string       `gorm:"-"` // Labels of the packages of the composition that were removed since.
+
+	PluginTimeout time.Duration       `gorm:"-"` // Default timeout of plugins that don't set their own; zero disables it.
+	AllowExisting bool                `gorm:"-"` // Whether the project may be created inside of an existing folder.
+	Conflict      render.ConflictFunc `gorm:"-"` // Resolves conflicts with existing files; nil overwrites them.
+
+	createdFolder bool     // Whether Create created the project folder; only then Rollback removes it.
+	pluginsRun    []string // Paths of the plugins that Create ran.
 }
 
-// NewProject returns a new project.
+// NewProject returns a new project. If the package was composed of several packages, their labels are recorded; see
+// Compose.
 func NewProject(name, path string, pkg *Package) *Project {
-	return &Project{
+	project := &Project{
 		Name:    name,
 		Path:    path,
 		Package: pkg,
 	}
+	if pkg != nil && len(pkg.composition) > 1 {
+		project.Packages = pkg.composition
+	}
+	return project
 }
 
-// Create starts the creation of a project.
-func (p *Project) Create(baseConfigPath string) (err error) {
-	err = p.createProjectFolder()
+// Create starts the creation of a project. It executes the steps of the project's plan in order. Running plugins are
+// aborted when ctx is cancelled. If AllowExisting is set, the project may be created inside of an existing folder and
+// Conflict decides what happens to existing files that templates would replace.
+//
+// Create doesn't change the working directory of the process; everything is created relative to the project path.
+// Thus multiple projects may be created at the same time. Once all steps succeeded, a snapshot of the generated files
+// and the manifest of the project are written to the project's state folder; see PrepareUpdate and Manifest.
+func (p *Project) Create(ctx context.Context, baseConfigPath string) error {
+	path, err := filepath.Abs(p.Path)
 	if err != nil {
 		return err
 	}
+	p.Path = path
 
-	// Get working directory. We will be changing directories, so we need to know, where we started from.
-	workingDirectory, err := os.Getwd()
+	plan, err := p.Plan(baseConfigPath)
 	if err != nil {
 		return err
 	}
 
-	// Chdir into the new project folder and defer chdir back to old cwd
-	err = os.Chdir(p.Path)
+	err = p.createProjectFolder()
 	if err != nil {
 		return err
 	}
 
+	env := p.PluginEnv(baseConfigPath)
+	data := p.TemplateData()
+	writer := &render.Writer{Conflict: p.Conflict}
 	defer func() {
-		newErr := os.Chdir(workingDirectory)
-		if newErr != nil {
-			err = newErr
+		for _, path := range writer.Skipped() {
+			p.Skipped = append(p.Skipped, fmt.Sprintf("existing file %s", p.relativePath(path)))
+		}
+		for _, backup := range writer.Backups() {
+			p.Backups = append(p.Backups, fmt.Sprintf("%s to %s", p.relativePath(backup.Path), p.relativePath(backup.Backup)))
 		}
 	}()
-
-	err = p.preRunPlugins(baseConfigPath)
-	if err != nil {
-		return err
+	for _, step := range plan.Steps {
+		err = p.executeStep(ctx, step, env, data, writer)
+		if err != nil {
+			return err
+		}
 	}
 
-	err = p.createFilesAndFolders(baseConfigPath)
-	if err != nil {
-		return err
+	skipped := make([]string, 0, len(writer.Skipped()))
+	for _, path := range writer.Skipped() {
+		skipped = append(skipped, p.relativePath(path))
 	}
-
-	return p.postRunPlugins(baseConfigPath)
+	return p.writeSnapshot(plan, data, skipped)
 }
 
-// createProjectFolder tries to create the main project folder.
-func (p *Project) createProjectFolder() error {
-	return os.Mkdir(p.Path, os.ModePerm)
+// executeStep executes a single step of the project's plan. Steps whose condition is not met are skipped.
+func (p *Project) executeStep(
+	ctx context.Context,
+	step *Step,
+	env *plugin.Env,
+	data *render.Data,
+	writer *render.Writer,
+) error {
+	switch {
+	case step.Skipped && step.Plugin != nil:
+		p.Skipped = append(p.Skipped, fmt.Sprintf("plugin %s (when %s)", step.Plugin.Path, step.Condition()))
+		return nil
+	case step.Skipped:
+		p.Skipped = append(p.Skipped, fmt.Sprintf("template %s (when %s)", step.Template.Destination, step.Condition()))
+		return nil
+	case step.Plugin != nil:
+		return p.runPlugin(ctx, step.Plugin, env)
+	default:
+		return createFromTemplate(p.Path, step, data, writer)
+	}
 }
 
-func (p *Project) createFilesAndFolders(baseConfigPath string) error {
-	baseTemplatesPath := filepath.Join(baseConfigPath, "/templates/")
-	for _, template := range p.Package.Templates {
-		if len(template.Path) > 0 {
-			// Copy template file or folder
-			err := copy.Copy(filepath.Join(baseTemplatesPath, template.Path), template.Destination)
-			if err != nil {
-				return err
-			}
-		}
-		if template.IsFile {
-			// Create file
-			_, err := os.Create(template.Destination)
-			if err != nil {
-				return err
-			}
-		} else {
-			// Create folder
-			err := os.MkdirAll(template.Destination, os.ModePerm)
-			if err != nil {
-				return err
-			}
-		}
+// Rollback removes the project folder and everything in it if the folder was created by Create. It is meant to clean
+// up after a failed creation; existing folders and folders of projects that were allowed to be created inside of an
+// existing folder are never removed.
+func (p *Project) Rollback() error {
+	if !p.createdFolder || p.AllowExisting {
+		return nil
+	}
+	err := os.RemoveAll(p.Path)
+	if err != nil {
+		return err
 	}
+	p.createdFolder = false
 	return nil
 }
 
-func (p *Project) preRunPlugins(baseConfigPath string) error {
-	basePluginsPath := filepath.Join(baseConfigPath, "plugins")
-	for _, plugin := range p.Package.Plugins {
-		if plugin.ExecNumber >= 0 {
-			continue
+// createProjectFolder tries to create the main project folder and its missing parent folders. An existing project
+// folder is only accepted if AllowExisting is set.
+func (p *Project) createProjectFolder() error {
+	if p.AllowExisting {
+		info, err := os.Stat(p.Path)
+		if err == nil && !info.IsDir() {
+			return fmt.Errorf("%s is not a folder", p.Path)
 		}
-		// Plugin path is relative by default to make it shareable. We have to make it an absolute path here,
-		// so that we can execute it.
-		plugin.Path = filepath.Join(basePluginsPath, plugin.Path)
-		err := plugin.Run()
-		if err != nil {
+		if err == nil {
+			return nil
+		}
+		if !os.IsNotExist(err) {
 			return err
 		}
 	}
+	err := os.MkdirAll(filepath.Dir(p.Path), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	err = os.Mkdir(p.Path, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	p.createdFolder = true
 	return nil
 }
 
-func (p *Project) postRunPlugins(baseConfigPath string) error {
-	basePluginsPath := filepath.Join(baseConfigPath, "plugins")
-	for _, plugin := range p.Package.Plugins {
-		if plugin.ExecNumber <= 0 {
-			continue
-		}
-		// Plugin path is relative by default to make it shareable. We have to make it an absolute path here,
-		// so that we can execute it.
-		plugin.Path = filepath.Join(basePluginsPath, plugin.Path)
-		err := plugin.Run()
-		if err != nil {
-			return err
+// createFromTemplate creates the file, folder or symlink of a template step at its destination inside of the folder
+// root and applies the mode of the template. Templates with inline content or that point to a template file or folder
+// get rendered unless they are raw, all others are created empty. Existing files that the writer kept are not touched.
+func createFromTemplate(root string, step *Step, data *render.Data, writer *render.Writer) error {
+	var err error
+	template, destination := step.Template, filepath.Join(root, step.Destination)
+	switch {
+	case step.Kind == StepSymlink:
+		// Create symbolic link
+		return writer.Symlink(step.Source, destination)
+	case len(template.Content) > 0 && template.Raw:
+		// Write inline template content
+		err = writer.WriteFile(destination, []byte(template.Content))
+	case len(template.Content) > 0:
+		// Render inline template content
+		err = writer.Inline(template.Content, destination, data)
+	case len(step.Source) > 0:
+		// Render or copy template file or folder
+		if template.Raw {
+			data = nil
 		}
+		err = renderTemplate(step.Source, destination, data, writer)
+	case template.IsFile:
+		// Create file
+		err = writer.WriteFile(destination, nil)
+	default:
+		// Create folder
+		err = os.MkdirAll(destination, os.ModePerm)
+	}
+	if err != nil {
+		return err
+	}
+
+	mode, hasMode, err := template.FileMode()
+	if err != nil || !hasMode || writer.IsSkipped(destination) {
+		return err
+	}
+	return os.Chmod(destination, mode)
+}
+
+// relativePath returns path relative to the project folder. Paths outside of it are returned unchanged.
+func (p *Project) relativePath(path string) string {
+	rel, err := filepath.Rel(p.Path, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return rel
+}
+
+// conditionMet evaluates the given condition against the project variables. An empty condition is always met.
+func (p *Project) conditionMet(condition string) (bool, error) {
+	if condition == "" {
+		return true, nil
+	}
+	met, err := expr.Eval(condition, p.Variables)
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate condition '%s', %s", condition, err.Error())
+	}
+	return met, nil
+}
+
+// TemplateData returns the data that is accessible from inside of the project's templates.
+func (p *Project) TemplateData() *render.Data {
+	return render.NewData(p.Name, p.Path, p.Package.Name, p.Package.Label, p.Variables)
+}
+
+// PluginEnv returns the environment that plugins of the project are executed in.
+func (p *Project) PluginEnv(baseConfigPath string) *plugin.Env {
+	return &plugin.Env{
+		Data:          p.TemplateData(),
+		TemplatesPath: filepath.Join(baseConfigPath, "templates"),
+		PluginsPath:   filepath.Join(baseConfigPath, "plugins"),
+		Sandboxed:     p.Package.Sandboxed,
+		Capabilities:  p.Package.Capabilities,
+		Timeout:       p.PluginTimeout,
+	}
+}
+
+// renderTemplate renders the template file or folder src to dst. If data is nil, src is copied without rendering it.
+func renderTemplate(src, dst string, data *render.Data, writer *render.Writer) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	switch {
+	case info.IsDir() && data == nil:
+		return writer.CopyDir(src, dst)
+	case info.IsDir():
+		return writer.Dir(src, dst, data)
+	case data == nil:
+		return writer.CopyFile(src, dst)
+	default:
+		return writer.File(src, dst, data)
+	}
+}
+
+// runPlugin runs a plugin of the project. If the plugin fails, its failure policy decides whether the creation is
+// aborted. A cancelled creation is always aborted.
+func (p *Project) runPlugin(ctx context.Context, plugin *Plugin, env *plugin.Env) error {
+	err := plugin.Run(ctx, env)
+	if ctx.Err() != nil {
+		return err
+	}
+	p.pluginsRun = append(p.pluginsRun, plugin.Path)
+	if err == nil {
+		return nil
+	}
+	switch plugin.FailurePolicy() {
+	case FailureContinue:
+		return nil
+	case FailureWarn:
+		p.Warnings = append(p.Warnings, err.Error())
+		return nil
+	default:
+		return err
 	}
-	return nil
 }
@@ -0,0 +1,145 @@
+package models
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nikoksr/proji/render"
+	"github.com/stretchr/testify/assert"
+)
+
+// newPluginsFolder returns a new temporary config folder whose plugins folder holds a shell plugin that fails and one
+// that creates the file after.txt in the project folder.
+func newPluginsFolder(t *testing.T) string {
+	baseConfigPath, err := ioutil.TempDir("", "proji-models")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(baseConfigPath) })
+
+	pluginsPath := filepath.Join(baseConfigPath, "plugins")
+	assert.NoError(t, os.Mkdir(pluginsPath, os.ModePerm))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(pluginsPath, "fail.sh"), []byte("exit 3\n"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(pluginsPath, "after.sh"), []byte("touch after.txt\n"), 0755))
+	return baseConfigPath
+}
+
+func TestCreateRawTemplates(t *testing.T) {
+	workflow := "steps:\n  - run: echo ${{ secrets.TOKEN }}\n"
+	templatesDir, err := ioutil.TempDir("", "proji-models")
+	assert.NoError(t, err)
+	defer os.RemoveAll(templatesDir)
+	assert.NoError(t, os.MkdirAll(filepath.Join(templatesDir, "templates", "github"), os.ModePerm))
+	ci := filepath.Join(templatesDir, "templates", "github", "ci.yml")
+	assert.NoError(t, ioutil.WriteFile(ci, []byte(workflow), 0644))
+
+	pkg := testPackage()
+	pkg.Templates = append(pkg.Templates,
+		&Template{Destination: "ci.yml", IsFile: true, Path: "github/ci.yml", Raw: true},
+		&Template{Destination: ".github", Path: "github", Raw: true},
+		&Template{Destination: "release.yml", IsFile: true, Content: workflow, Raw: true},
+	)
+	project := NewProject("my-project", filepath.Join(templatesDir, "my-project"), pkg)
+	assert.NoError(t, project.Create(context.Background(), templatesDir))
+
+	for _, path := range []string{"ci.yml", filepath.Join(".github", "ci.yml"), "release.yml"} {
+		content, err := ioutil.ReadFile(filepath.Join(project.Path, path))
+		assert.NoError(t, err, path)
+		assert.Equal(t, workflow, string(content), path)
+	}
+
+	// The same templates fail to render without raw
+	pkg.Templates[len(pkg.Templates)-1].Raw = false
+	project = NewProject("other-project", filepath.Join(templatesDir, "other-project"), pkg)
+	assert.Error(t, project.Create(context.Background(), templatesDir))
+}
+
+func TestCreatePluginFailures(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       string
+		cancelled    bool
+		wantErr      bool
+		wantWarnings int
+		wantAfter    bool // Whether the plugin after the failing one ran
+	}{
+		{name: "Default", wantErr: true},
+		{name: "Abort", policy: FailureAbort, wantErr: true},
+		{name: "Warn", policy: FailureWarn, wantWarnings: 1, wantAfter: true},
+		{name: "Continue", policy: FailureContinue, wantAfter: true},
+		{name: "Cancelled", policy: FailureContinue, cancelled: true, wantErr: true},
+	}
+
+	for _, test := range tests {
+		baseConfigPath := newPluginsFolder(t)
+		pkg := testPackage()
+		pkg.Plugins = []*Plugin{
+			{Path: "fail.sh", ExecNumber: 1, OnFailure: test.policy},
+			{Path: "after.sh", ExecNumber: 2},
+		}
+		project := NewProject("my-project", filepath.Join(baseConfigPath, "my-project"), pkg)
+		ctx, cancel := context.WithCancel(context.Background())
+		if test.cancelled {
+			cancel()
+		}
+
+		err := project.Create(ctx, baseConfigPath)
+		cancel()
+		if test.wantErr {
+			assert.Error(t, err, test.name)
+		} else {
+			assert.NoError(t, err, test.name)
+			assert.Equal(t, StringList{"fail.sh", "after.sh"}, project.Manifest.Plugins, test.name)
+		}
+		assert.Len(t, project.Warnings, test.wantWarnings, test.name)
+		_, err = os.Stat(filepath.Join(project.Path, "after.txt"))
+		assert.Equal(t, test.wantAfter, err == nil, test.name)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	tests := []struct {
+		name          string
+		existing      bool // Whether the project folder exists before the creation
+		allowExisting bool
+		wantFolder    bool // Whether the project folder exists after the rollback
+	}{
+		{name: "New folder"},
+		{name: "Existing folder", existing: true, wantFolder: true},
+		{name: "Into existing folder", existing: true, allowExisting: true, wantFolder: true},
+		{name: "Into new folder", allowExisting: true, wantFolder: true},
+	}
+
+	for _, test := range tests {
+		baseConfigPath := newPluginsFolder(t)
+		pkg := testPackage()
+		pkg.Plugins = []*Plugin{{Path: "after.sh", ExecNumber: 1}, {Path: "fail.sh", ExecNumber: 2}}
+		project := NewProject("my-project", filepath.Join(baseConfigPath, "projects", "my-project"), pkg)
+		project.AllowExisting = test.allowExisting
+		project.Conflict = func(string, []byte, []byte) (string, error) { return render.ConflictOverwrite, nil }
+		existingFile := filepath.Join(project.Path, "main.py")
+		if test.existing {
+			assert.NoError(t, os.MkdirAll(project.Path, os.ModePerm), test.name)
+			assert.NoError(t, ioutil.WriteFile(existingFile, []byte("print('hello')\n"), 0644), test.name)
+		}
+
+		assert.Error(t, project.Create(context.Background(), baseConfigPath), test.name)
+		assert.NoError(t, project.Rollback(), test.name)
+		if !test.wantFolder {
+			assert.NoDirExists(t, project.Path, test.name)
+			assert.DirExists(t, filepath.Dir(project.Path), test.name)
+			continue
+		}
+		assert.DirExists(t, project.Path, test.name)
+		if test.allowExisting {
+			assert.FileExists(t, filepath.Join(project.Path, "README.md"), test.name)
+		}
+		if test.existing {
+			assert.FileExists(t, existingFile, test.name)
+		}
+		// Rolling back twice doesn't remove anything either
+		assert.NoError(t, project.Rollback(), test.name)
+		assert.DirExists(t, project.Path, test.name)
+	}
+}
@@ -0,0 +1,188 @@
+package models
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/nikoksr/proji/render"
+)
+
+// Folders and files that proji keeps inside of every project it creates.
+const (
+	StateFolder  = ".proji"        // Folder of the project state, relative to the project folder.
+	baseFolder   = "base"          // Snapshot of the files the templates generated, relative to the state folder.
+	manifestFile = "manifest.toml" // Manifest of the project, relative to the state folder; see Manifest.
+)
+
+// snapshotEntry is a file, folder or symlink of a snapshot.
+type snapshotEntry struct {
+	content   []byte // Content of a file or target of a symlink.
+	mode      os.FileMode
+	isDir     bool
+	isSymlink bool
+}
+
+// equal reports whether both entries are of the same kind and have the same content. Modes are not compared.
+func (e *snapshotEntry) equal(other *snapshotEntry) bool {
+	return e.isDir == other.isDir && e.isSymlink == other.isSymlink && string(e.content) == string(other.content)
+}
+
+// basePath returns the path of the snapshot of the files that were generated for the project.
+func (p *Project) basePath() string {
+	return filepath.Join(p.Path, StateFolder, baseFolder)
+}
+
+// writeSnapshot renders the templates of the plan into the snapshot folder of the project and writes the manifest of
+// the project. The snapshot is the common ancestor of the project and a newer version of its package when the project
+// gets updated. Skipped holds the paths of existing files, relative to the project folder, that were kept instead of
+// being generated; the manifest records them as kept.
+func (p *Project) writeSnapshot(plan *Plan, data *render.Data, skipped []string) error {
+	err := os.RemoveAll(p.basePath())
+	if err != nil {
+		return err
+	}
+	err = p.renderTemplates(p.basePath(), plan, data)
+	if err != nil {
+		return fmt.Errorf("failed to write snapshot, %s", err.Error())
+	}
+	files, err := readSnapshot(p.basePath())
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot, %s", err.Error())
+	}
+	p.Manifest = p.newManifest(data, files, skipped, p.pluginsRun)
+	return p.writeManifest()
+}
+
+// renderTemplates renders the templates of the plan into root. Skipped templates and plugins are ignored.
+func (p *Project) renderTemplates(root string, plan *Plan, data *render.Data) error {
+	writer := new(render.Writer)
+	for _, step := range plan.Steps {
+		if step.Kind == StepPlugin || step.Skipped {
+			continue
+		}
+		err := createFromTemplate(root, step, data, writer)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generate renders the templates of the project's package with the given data into a temporary folder and returns the
+// result, keyed by the path relative to the project folder. The project itself is not touched.
+func (p *Project) generate(baseConfigPath string, data *render.Data) (map[string]*snapshotEntry, error) {
+	plan, err := p.Plan(baseConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	tmp, err := ioutil.TempDir("", "proji-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+	err = p.renderTemplates(tmp, plan, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render package, %s", err.Error())
+	}
+	return readSnapshot(tmp)
+}
+
+// LoadValues loads the values of the package variables that the project was created with from its manifest. The
+// values are returned unparsed, as they would be entered by the user. Projects that were created by older versions of
+// proji have no manifest; an empty map is returned for them.
+func (p *Project) LoadValues() (map[string]string, error) {
+	values := make(map[string]string)
+	manifest, err := ReadManifest(p.Path)
+	if err != nil || manifest == nil {
+		return values, err
+	}
+	for name, value := range manifest.Variables {
+		values[name] = fmt.Sprint(value)
+	}
+	return values, nil
+}
+
+// readSnapshot reads all files, folders and symlinks inside of root, keyed by their path relative to root. An empty
+// snapshot is returned if root doesn't exist.
+func readSnapshot(root string) (map[string]*snapshotEntry, error) {
+	entries := make(map[string]*snapshotEntry)
+	if _, err := os.Lstat(root); os.IsNotExist(err) {
+		return entries, nil
+	}
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		entry, err := readEntry(path, info)
+		if err != nil {
+			return err
+		}
+		entries[rel] = entry
+		return nil
+	})
+	return entries, err
+}
+
+// readEntry reads the file, folder or symlink at path. It returns nil if nothing exists at path.
+func readEntry(path string, info os.FileInfo) (*snapshotEntry, error) {
+	if info == nil {
+		var err error
+		info, err = os.Lstat(path)
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	entry := &snapshotEntry{mode: info.Mode().Perm()}
+	switch {
+	case info.IsDir():
+		entry.isDir = true
+	case info.Mode()&os.ModeSymlink != 0:
+		entry.isSymlink = true
+		target, err := os.Readlink(path)
+		if err != nil {
+			return nil, err
+		}
+		entry.content = []byte(target)
+	default:
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		entry.content = content
+	}
+	return entry, nil
+}
+
+// writeEntry writes the file, folder or symlink to path. Files and symlinks that exist at path are replaced.
+func writeEntry(path string, entry *snapshotEntry) error {
+	if entry.isDir {
+		return os.MkdirAll(path, os.ModePerm)
+	}
+	err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if entry.isSymlink {
+		return os.Symlink(string(entry.content), path)
+	}
+	err = ioutil.WriteFile(path, entry.content, entry.mode)
+	if err != nil {
+		return err
+	}
+	// Chmod explicitly, the mode passed on creation is subject to the umask.
+	return os.Chmod(path, entry.mode)
+}
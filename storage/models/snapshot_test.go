@@ -0,0 +1,90 @@
+package models
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nikoksr/proji/render"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteSnapshot(t *testing.T) {
+	pkg := testPackage()
+	pkg.Variables = []*Variable{{Name: "license", Type: "string", Default: "MIT"}}
+	pkg.Templates = append(pkg.Templates,
+		&Template{Destination: "LICENSE", IsFile: true, Content: "{{ .Vars.license }}\n"},
+		&Template{Destination: "setup.py", IsFile: true, Content: "setup()\n", When: "false"},
+	)
+	project := NewProject("my-project", "", pkg)
+	project.Variables = render.Vars{"license": "GPL"}
+	tmpDir, err := ioutil.TempDir("", "proji-models")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+	project.Path = filepath.Join(tmpDir, "my-project")
+	assert.NoError(t, os.Mkdir(project.Path, os.ModePerm))
+
+	plan, err := project.Plan(tmpDir)
+	assert.NoError(t, err)
+	assert.NoError(t, project.writeSnapshot(plan, project.TemplateData(), []string{"LICENSE"}))
+
+	// The snapshot holds everything the templates generate, including kept files
+	snapshot, err := readSnapshot(project.basePath())
+	assert.NoError(t, err)
+	want := map[string]*snapshotEntry{
+		"src":       {isDir: true},
+		"README.md": {content: []byte("# my-project\n")},
+		"docs":      {content: []byte("README.md"), isSymlink: true},
+		"LICENSE":   {content: []byte("GPL\n")},
+	}
+	assert.Len(t, snapshot, len(want))
+	for path, entry := range want {
+		if assert.Contains(t, snapshot, path) {
+			assert.True(t, entry.equal(snapshot[path]), path)
+		}
+	}
+
+	// The manifest only lists what was generated
+	assert.Equal(t, StringList{"LICENSE"}, project.Manifest.Kept)
+	assert.Len(t, project.Manifest.Files, 3)
+	manifest, err := ReadManifest(project.Path)
+	assert.NoError(t, err)
+	assert.Len(t, manifest.Files, 3)
+
+	// The values are stored unparsed
+	values, err := project.LoadValues()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"license": "GPL"}, values)
+}
+
+func TestReadSnapshot(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "proji-models")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	snapshot, err := readSnapshot(filepath.Join(tmpDir, "missing"))
+	assert.NoError(t, err)
+	assert.Empty(t, snapshot)
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "src", "pkg"), os.ModePerm))
+	main := filepath.Join(tmpDir, "src", "main.go")
+	assert.NoError(t, ioutil.WriteFile(main, []byte("package main\n"), 0644))
+	assert.NoError(t, os.Chmod(main, 0755))
+	assert.NoError(t, os.Symlink(filepath.Join("src", "main.go"), filepath.Join(tmpDir, "main")))
+	snapshot, err = readSnapshot(tmpDir)
+	assert.NoError(t, err)
+	want := map[string]*snapshotEntry{
+		"src":                           {isDir: true},
+		filepath.Join("src", "pkg"):     {isDir: true},
+		filepath.Join("src", "main.go"): {content: []byte("package main\n")},
+		"main":                          {content: []byte(filepath.Join("src", "main.go")), isSymlink: true},
+	}
+	assert.Len(t, snapshot, len(want))
+	for path, entry := range want {
+		if assert.Contains(t, snapshot, path) {
+			assert.True(t, entry.equal(snapshot[path]), path)
+		}
+	}
+	assert.Equal(t, os.FileMode(0755), snapshot[filepath.Join("src", "main.go")].mode)
+}
@@ -1,6 +1,9 @@
 package models
 
 import (
+	"fmt"
+	"os"
+	"strconv"
 	"time"
 
 	"gorm.io/gorm"
@@ -14,7 +17,55 @@ type Template struct {
 	UpdatedAt   time.Time      `toml:"-"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" toml:"-"`
 	IsFile      bool           `gorm:"not null" toml:"is_file"`
-	Path        string         `gorm:"index:idx_template_path_destination,unique;not null" toml:"path"`
-	Destination string         `gorm:"index:idx_template_path_destination,unique;not null" toml:"destination"`
+	Path        string         `gorm:"index;not null" toml:"path"`
+	Destination string         `gorm:"not null" toml:"destination"`
+	Content     string         `gorm:"type:text" toml:"content,omitempty"`
+	Symlink     string         `gorm:"size:255" toml:"symlink,omitempty"`
+	Mode        string         `gorm:"size:4" toml:"mode,omitempty"`
 	Description string         `gorm:"size:255" toml:"description"`
+	When        string         `gorm:"size:255" toml:"when,omitempty"`
+	Raw         bool           `gorm:"not null;default:false" toml:"raw,omitempty"`
+	Inherited   string         `gorm:"-" toml:"-"` // Label of the package it was inherited from; see ResolveExtends.
+}
+
+// Validate checks that the template definition is valid.
+func (t *Template) Validate() error {
+	if t.Destination == "" {
+		return fmt.Errorf("template destination cannot be an empty string")
+	}
+
+	_, _, err := t.FileMode()
+	if err != nil {
+		return err
+	}
+
+	sources := 0
+	for _, source := range []string{t.Path, t.Content, t.Symlink} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return fmt.Errorf("template %s may only define one of path, content and symlink", t.Destination)
+	}
+	if t.Content != "" && !t.IsFile {
+		return fmt.Errorf("template %s defines inline content but is not a file", t.Destination)
+	}
+	if t.Symlink != "" && t.Mode != "" {
+		return fmt.Errorf("template %s is a symlink and cannot define a mode", t.Destination)
+	}
+	return nil
+}
+
+// FileMode parses the octal mode of the template; e.g. "0755". The returned bool is false if the template defines no
+// mode.
+func (t *Template) FileMode() (os.FileMode, bool, error) {
+	if t.Mode == "" {
+		return 0, false, nil
+	}
+	mode, err := strconv.ParseUint(t.Mode, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, false, fmt.Errorf("template %s has invalid mode '%s', expected octal permissions like 0755", t.Destination, t.Mode)
+	}
+	return os.FileMode(mode), true, nil
 }
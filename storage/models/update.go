@@ -0,0 +1,221 @@
+package models
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/nikoksr/proji/diff"
+	"github.com/nikoksr/proji/render"
+)
+
+// Actions that an update takes for a single file of a project.
+const (
+	UpdateAdd      = "add"      // The file is new in the package.
+	UpdateReplace  = "replace"  // The file changed in the package but not in the project.
+	UpdateMerge    = "merge"    // The file changed in the package and in the project; the changes were merged.
+	UpdateConflict = "conflict" // Like merge but the changes conflict; the conflicts are marked in the file.
+	UpdateRemove   = "remove"   // The file was removed from the package and not changed in the project.
+	UpdateKeep     = "keep"     // The file is left untouched although the package changed; see Reason.
+)
+
+// FileUpdate describes how a single file, folder or symlink of a project is updated.
+type FileUpdate struct {
+	Path      string // Path relative to the project folder.
+	Action    string
+	Reason    string // Why the file is kept; keep actions only.
+	Current   []byte // Current content of the file or target of the symlink; nil if it doesn't exist.
+	Content   []byte // Content of the file or target of the symlink after the update.
+	IsDir     bool
+	IsSymlink bool
+	Binary    bool // Whether the current or the new content is binary.
+
+	entry *snapshotEntry // What gets written to Path.
+}
+
+// Update is the set of changes that re-applies the current version of a package to a project that was created from an
+// older version of it.
+type Update struct {
+	Files []*FileUpdate
+
+	project   *Project
+	data      *render.Data              // What the package was rendered with.
+	generated map[string]*snapshotEntry // What the package generates now; the snapshot after the update.
+}
+
+// PrepareUpdate compares what the package of the project would generate now with what it generated when the project
+// was created or last updated, and with what the project contains. Files that only changed in the package are replaced,
+// files that changed on both sides are merged line by line. The project is not touched until the update is applied.
+//
+// Only templates are considered; plugins are not run again. The variables of the project must be set. The templates
+// are rendered with the date and user recorded in the manifest, so they only differ if the package changed.
+func (p *Project) PrepareUpdate(baseConfigPath string) (*Update, error) {
+	manifest, err := p.readManifest()
+	if err != nil {
+		return nil, err
+	}
+	data := p.renderData(manifest)
+	generated, err := p.generate(baseConfigPath, data)
+	if err != nil {
+		return nil, err
+	}
+	base, err := readSnapshot(p.basePath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot, %s", err.Error())
+	}
+
+	// Compare every file that was generated before or is generated now
+	paths := make([]string, 0, len(generated)+len(base))
+	for path := range generated {
+		paths = append(paths, path)
+	}
+	for path := range base {
+		if _, ok := generated[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	update := &Update{Files: make([]*FileUpdate, 0), project: p, data: data, generated: generated}
+	for _, path := range paths {
+		current, err := readEntry(filepath.Join(p.Path, path), nil)
+		if err != nil {
+			return nil, err
+		}
+		file := compareFile(path, base[path], current, generated[path])
+		if file != nil {
+			update.Files = append(update.Files, file)
+		}
+	}
+	return update, nil
+}
+
+// compareFile decides what happens to a file given its base version, its current version in the project and the
+// version that the package generates now. Each of them may be nil if the file doesn't exist. Nil is returned if nothing
+// needs to be done.
+func compareFile(path string, base, current, generated *snapshotEntry) *FileUpdate {
+	file := &FileUpdate{Path: path, entry: generated}
+	if current != nil {
+		file.Current = current.content
+		file.IsDir = current.isDir
+		file.IsSymlink = current.isSymlink
+	}
+	keep := func(reason string) *FileUpdate {
+		file.Action, file.Reason, file.Content = UpdateKeep, reason, file.Current
+		return file
+	}
+
+	// The package doesn't generate the file anymore
+	if generated == nil {
+		switch {
+		case current == nil || current.isDir:
+			return nil
+		case current.equal(base):
+			file.Action = UpdateRemove
+			return file
+		default:
+			return keep("removed from the package but changed in the project")
+		}
+	}
+
+	file.Content = generated.content
+	file.IsDir = generated.isDir
+	file.IsSymlink = generated.isSymlink
+	file.Binary = render.IsBinary(generated.content) || current != nil && render.IsBinary(current.content)
+	switch {
+	case current == nil && (base == nil || generated.isDir):
+		file.Action = UpdateAdd
+		return file
+	case current == nil && base.equal(generated):
+		return nil
+	case current == nil:
+		return keep("changed in the package but removed from the project")
+	case current.equal(generated) || generated.isDir:
+		return nil
+	case current.isDir:
+		return keep("changed in the package but is a folder in the project")
+	case base == nil:
+		return keep("new in the package but already exists in the project")
+	case current.equal(base):
+		file.Action = UpdateReplace
+		return file
+	case base.equal(generated):
+		return nil
+	case file.Binary || current.isSymlink || generated.isSymlink || base.isSymlink || base.isDir:
+		return keep("changed in the package and in the project")
+	}
+
+	merged, conflicts := diff.MergeText(string(base.content), string(current.content), string(generated.content),
+		"project", "package")
+	file.Action, file.Content = UpdateMerge, []byte(merged)
+	if conflicts > 0 {
+		file.Action = UpdateConflict
+	}
+	file.entry = &snapshotEntry{content: file.Content, mode: current.mode}
+	return file
+}
+
+// HasChanges reports whether applying the update would change any file of the project.
+func (u *Update) HasChanges() bool {
+	for _, file := range u.Files {
+		if file.Action != UpdateKeep {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply applies the update to the project. Afterwards the snapshot and the manifest of the project reflect the current
+// version of the package, so that the next update only brings in changes made after this one.
+func (u *Update) Apply() error {
+	p := u.project
+	updated := make(map[string]bool)
+	for _, file := range u.Files {
+		updated[file.Path] = file.Action != UpdateKeep
+		path := filepath.Join(p.Path, file.Path)
+		var err error
+		switch file.Action {
+		case UpdateAdd, UpdateReplace, UpdateMerge, UpdateConflict:
+			err = writeEntry(path, file.entry)
+		case UpdateRemove:
+			err = os.Remove(path)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update %s, %s", file.Path, err.Error())
+		}
+	}
+
+	// Replace the snapshot and the manifest. Plugins are not run on update, so the manifest keeps listing the plugins
+	// that ran on creation. Kept files stay kept unless the update replaced them.
+	plugins, kept := make([]string, 0), make([]string, 0)
+	manifest, err := ReadManifest(p.Path)
+	if err != nil {
+		return err
+	}
+	if manifest != nil {
+		plugins = manifest.Plugins
+		for _, path := range manifest.Kept {
+			if !updated[filepath.FromSlash(path)] {
+				kept = append(kept, filepath.FromSlash(path))
+			}
+		}
+	}
+	err = os.RemoveAll(p.basePath())
+	if err != nil {
+		return err
+	}
+	paths := make([]string, 0, len(u.generated))
+	for path := range u.generated {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		err = writeEntry(filepath.Join(p.basePath(), path), u.generated[path])
+		if err != nil {
+			return fmt.Errorf("failed to write snapshot, %s", err.Error())
+		}
+	}
+	p.Manifest = p.newManifest(u.data, u.generated, kept, plugins)
+	return p.writeManifest()
+}
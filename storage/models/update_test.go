@@ -0,0 +1,219 @@
+package models
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareFile(t *testing.T) {
+	file := func(content string) *snapshotEntry { return &snapshotEntry{content: []byte(content), mode: 0644} }
+	folder := &snapshotEntry{isDir: true}
+	symlink := &snapshotEntry{content: []byte("target"), isSymlink: true}
+
+	tests := []struct {
+		name        string
+		base        *snapshotEntry
+		current     *snapshotEntry
+		generated   *snapshotEntry
+		wantAction  string // Empty if nothing needs to be done
+		wantContent string
+	}{
+		{name: "Unchanged", base: file("a"), current: file("a"), generated: file("a")},
+		{name: "Changed in project only", base: file("a"), current: file("b"), generated: file("a")},
+		{
+			name:        "Changed in package only",
+			base:        file("a"),
+			current:     file("a"),
+			generated:   file("b"),
+			wantAction:  UpdateReplace,
+			wantContent: "b",
+		},
+		{name: "Changed the same way", base: file("a"), current: file("b"), generated: file("b")},
+		{name: "New in package", generated: file("a"), wantAction: UpdateAdd, wantContent: "a"},
+		{
+			name:        "New in package and project",
+			current:     file("b"),
+			generated:   file("a"),
+			wantAction:  UpdateKeep,
+			wantContent: "b",
+		},
+		{name: "Removed from project", base: file("a"), generated: file("a")},
+		{
+			name:       "Removed from project and changed in package",
+			base:       file("a"),
+			generated:  file("b"),
+			wantAction: UpdateKeep,
+		},
+		{name: "Removed from package", base: file("a"), current: file("a"), wantAction: UpdateRemove},
+		{
+			name:        "Removed from package and changed in project",
+			base:        file("a"),
+			current:     file("b"),
+			wantAction:  UpdateKeep,
+			wantContent: "b",
+		},
+		{name: "Removed from package and project", base: file("a")},
+		{name: "Folder", base: folder, current: folder, generated: folder},
+		{name: "Missing folder", base: folder, generated: folder, wantAction: UpdateAdd},
+		{name: "Folder in project", base: file("a"), current: folder, generated: file("b"), wantAction: UpdateKeep},
+		{
+			name:        "Symlink changed on both sides",
+			base:        symlink,
+			current:     file("b"),
+			generated:   file("c"),
+			wantAction:  UpdateKeep,
+			wantContent: "b",
+		},
+		{
+			name:        "Merge",
+			base:        file("a\nb\nc\n"),
+			current:     file("x\nb\nc\n"),
+			generated:   file("a\nb\ny\n"),
+			wantAction:  UpdateMerge,
+			wantContent: "x\nb\ny\n",
+		},
+		{
+			name:        "Conflict",
+			base:        file("a\nb\nc\n"),
+			current:     file("a\nx\nc\n"),
+			generated:   file("a\ny\nc\n"),
+			wantAction:  UpdateConflict,
+			wantContent: "a\n<<<<<<< project\nx\n=======\ny\n>>>>>>> package\nc\n",
+		},
+		{
+			name:        "Binary",
+			base:        file("a\x00"),
+			current:     file("b\x00"),
+			generated:   file("c\x00"),
+			wantAction:  UpdateKeep,
+			wantContent: "b\x00",
+		},
+	}
+
+	for _, test := range tests {
+		got := compareFile("file", test.base, test.current, test.generated)
+		if test.wantAction == "" {
+			assert.Nil(t, got, test.name)
+			continue
+		}
+		if assert.NotNil(t, got, test.name) {
+			assert.Equal(t, test.wantAction, got.Action, test.name)
+			if test.wantContent != "" {
+				assert.Equal(t, test.wantContent, string(got.Content), test.name)
+			}
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	pkg := testPackage()
+	pkg.Templates = append(pkg.Templates,
+		&Template{Destination: "main.py", IsFile: true, Content: "import os\n\nprint('hello')\n"},
+		&Template{Destination: "setup.py", IsFile: true, Content: "setup()\n"},
+	)
+	project := createTestProject(t, pkg, nil)
+	write := func(path, content string) {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(project.Path, path), []byte(content), 0644))
+	}
+	read := func(path string) string {
+		content, err := ioutil.ReadFile(filepath.Join(project.Path, path))
+		assert.NoError(t, err)
+		return string(content)
+	}
+
+	// Change the project and the package
+	write("main.py", "import sys\n\nprint('hello')\n")
+	write("README.md", "# My project\n")
+	pkg.Templates[1].Content = "# {{ .Name }}\n\nA python project.\n"
+	pkg.Templates[3].Content = "import os\n\nprint('hello world')\n"
+	pkg.Templates = append(pkg.Templates[:4], &Template{Destination: "tests", IsFile: false})
+
+	update, err := project.PrepareUpdate(filepath.Dir(project.Path))
+	assert.NoError(t, err)
+	assert.True(t, update.HasChanges())
+	actions := make(map[string]string)
+	for _, file := range update.Files {
+		actions[file.Path] = file.Action
+	}
+	assert.Equal(t, map[string]string{
+		"README.md": UpdateConflict,
+		"main.py":   UpdateMerge,
+		"setup.py":  UpdateRemove,
+		"tests":     UpdateAdd,
+	}, actions)
+
+	assert.NoError(t, update.Apply())
+	assert.Equal(t, "import sys\n\nprint('hello world')\n", read("main.py"))
+	conflict := "<<<<<<< project\n# My project\n=======\n# my-project\n\nA python project.\n>>>>>>> package\n"
+	assert.Equal(t, conflict, read("README.md"))
+	assert.NoFileExists(t, filepath.Join(project.Path, "setup.py"))
+	assert.DirExists(t, filepath.Join(project.Path, "tests"))
+
+	// The snapshot is the base of the next update; resolving the conflict leaves nothing to update
+	write("README.md", "# my-project\n\nA python project.\n")
+	update, err = project.PrepareUpdate(filepath.Dir(project.Path))
+	assert.NoError(t, err)
+	assert.False(t, update.HasChanges())
+
+	manifest, err := ReadManifest(project.Path)
+	assert.NoError(t, err)
+	paths := make([]string, 0, len(manifest.Files))
+	for _, file := range manifest.Files {
+		paths = append(paths, file.Path)
+	}
+	assert.Equal(t, []string{"README.md", "docs", "main.py", "src", "tests"}, paths)
+	_, err = os.Stat(filepath.Join(project.basePath(), "setup.py"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestUpdateKeepsDateAndUser(t *testing.T) {
+	pkg := testPackage()
+	pkg.Templates = append(pkg.Templates, &Template{
+		Destination: "LICENSE",
+		IsFile:      true,
+		Content:     "Copyright (c) {{ date \"2006\" .Date }} {{ .User }}\nGenerated at {{ .Date }}\n",
+	})
+	project := createTestProject(t, pkg, nil)
+	baseConfigPath := filepath.Dir(project.Path)
+
+	// Render the project as if it was created by someone else a while ago
+	data := project.TemplateData()
+	data.Date = time.Date(2020, 7, 12, 10, 30, 0, 0, time.UTC)
+	data.User = "tester"
+	plan, err := project.Plan(baseConfigPath)
+	assert.NoError(t, err)
+	assert.NoError(t, project.renderTemplates(project.Path, plan, data))
+	assert.NoError(t, project.writeSnapshot(plan, data, nil))
+	license := filepath.Join(project.Path, "LICENSE")
+	content, err := ioutil.ReadFile(license)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "Copyright (c) 2020 tester\n")
+	assert.NoError(t, ioutil.WriteFile(license, append(content, "Changed in the project\n"...), 0644))
+
+	// Neither the date nor the user is a change of the package
+	update, err := project.PrepareUpdate(baseConfigPath)
+	assert.NoError(t, err)
+	assert.False(t, update.HasChanges())
+
+	// Updates keep the recorded date and user
+	pkg.Templates[1].Content = "# {{ .Name }} by {{ .User }}\n"
+	update, err = project.PrepareUpdate(baseConfigPath)
+	assert.NoError(t, err)
+	assert.Len(t, update.Files, 1)
+	assert.Equal(t, "README.md", update.Files[0].Path)
+	assert.Equal(t, "# my-project by tester\n", string(update.Files[0].Content))
+	assert.NoError(t, update.Apply())
+	manifest, err := ReadManifest(project.Path)
+	assert.NoError(t, err)
+	assert.True(t, data.Date.Equal(manifest.GeneratedAt))
+	assert.Equal(t, "tester", manifest.User)
+
+	update, err = project.PrepareUpdate(baseConfigPath)
+	assert.NoError(t, err)
+	assert.False(t, update.HasChanges())
+}
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Supported variable types.
+const (
+	VariableTypeString = "string"
+	VariableTypeBool   = "bool"
+	VariableTypeInt    = "int"
+	VariableTypeChoice = "choice"
+)
+
+// variableNamePattern defines valid variable names. Names have to be valid identifiers so that they can be accessed
+// from templates and plugins.
+var variableNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Variable represents a value that is asked for during the creation of a project. Its value is passed to templates,
+// destinations and plugins. It holds tags for gorm and toml defining its storage and export/import behaviour.
+type Variable struct {
+	ID        uint           `gorm:"primarykey" toml:"-"`
+	CreatedAt time.Time      `toml:"-"`
+	UpdatedAt time.Time      `toml:"-"`
+	DeletedAt gorm.DeletedAt `gorm:"index" toml:"-"`
+	Name      string         `gorm:"not null;size:64" toml:"name"`
+	Prompt    string         `gorm:"size:255" toml:"prompt,omitempty"`
+	Type      string         `gorm:"not null;size:16" toml:"type"`
+	Default   string         `gorm:"size:255" toml:"default,omitempty"`
+	Choices   StringList     `gorm:"type:text" toml:"choices,omitempty"`
+	Regex     string         `gorm:"size:255" toml:"regex,omitempty"`
+	Inherited string         `gorm:"-" toml:"-"` // Label of the package it was inherited from; see ResolveExtends.
+}
+
+// Validate checks that the variable definition is valid. It does not validate any user input.
+func (v *Variable) Validate() error {
+	if !variableNamePattern.MatchString(v.Name) {
+		return fmt.Errorf("invalid variable name '%s'", v.Name)
+	}
+
+	// Default to type string
+	if v.Type == "" {
+		v.Type = VariableTypeString
+	}
+
+	switch v.Type {
+	case VariableTypeString, VariableTypeBool, VariableTypeInt:
+	case VariableTypeChoice:
+		if len(v.Choices) < 1 {
+			return fmt.Errorf("variable '%s' is of type choice but defines no choices", v.Name)
+		}
+	default:
+		return fmt.Errorf("variable '%s' has unsupported type '%s'", v.Name, v.Type)
+	}
+
+	if v.Regex != "" {
+		_, err := regexp.Compile(v.Regex)
+		if err != nil {
+			return fmt.Errorf("variable '%s' has an invalid regex, %s", v.Name, err.Error())
+		}
+	}
+
+	if v.Default != "" {
+		_, err := v.Parse(v.Default)
+		if err != nil {
+			return fmt.Errorf("variable '%s' has an invalid default value, %s", v.Name, err.Error())
+		}
+	}
+	return nil
+}
+
+// Parse validates the given input and converts it to the variable's type. An empty input falls back to the default
+// value and is rejected if the variable is required.
+func (v *Variable) Parse(input string) (interface{}, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		if v.IsRequired() {
+			return nil, fmt.Errorf("a value is required")
+		}
+		input = v.Default
+	}
+
+	if v.Regex != "" {
+		matched, err := regexp.MatchString(v.Regex, input)
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			return nil, fmt.Errorf("value '%s' does not match the pattern '%s'", input, v.Regex)
+		}
+	}
+
+	switch v.Type {
+	case VariableTypeBool:
+		return strconv.ParseBool(input)
+	case VariableTypeInt:
+		return strconv.Atoi(input)
+	case VariableTypeChoice:
+		for _, choice := range v.Choices {
+			if input == choice {
+				return input, nil
+			}
+		}
+		return nil, fmt.Errorf("value '%s' is not one of %s", input, strings.Join(v.Choices, ", "))
+	default:
+		return input, nil
+	}
+}
+
+// IsRequired reports whether a value has to be given for the variable. Variables without a default value are required.
+func (v *Variable) IsRequired() bool {
+	return v.Default == ""
+}
+
+// Question returns the text that is shown when asking the user for a value.
+func (v *Variable) Question() string {
+	question := v.Prompt
+	if question == "" {
+		question = v.Name
+	}
+	if v.Type == VariableTypeChoice {
+		question += " (" + strings.Join(v.Choices, "|") + ")"
+	}
+	if v.Default != "" {
+		question += " [" + v.Default + "]"
+	}
+	return "> " + question + ": "
+}
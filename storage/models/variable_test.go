@@ -0,0 +1,108 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVariableValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		variable *Variable
+		wantType string
+		wantErr  bool
+	}{
+		{name: "String", variable: &Variable{Name: "license", Type: "string"}, wantType: VariableTypeString},
+		{name: "Default type", variable: &Variable{Name: "license"}, wantType: VariableTypeString},
+		{name: "Bool", variable: &Variable{Name: "use_docker", Type: "bool", Default: "true"}, wantType: VariableTypeBool},
+		{name: "Int", variable: &Variable{Name: "port", Type: "int", Default: "8080"}, wantType: VariableTypeInt},
+		{
+			name:     "Choice",
+			variable: &Variable{Name: "license", Type: "choice", Choices: StringList{"MIT", "GPL"}, Default: "MIT"},
+			wantType: VariableTypeChoice,
+		},
+		{name: "Invalid name", variable: &Variable{Name: "my-license"}, wantErr: true},
+		{name: "Empty name", variable: &Variable{}, wantErr: true},
+		{name: "Unknown type", variable: &Variable{Name: "ratio", Type: "float"}, wantErr: true},
+		{name: "Choice without choices", variable: &Variable{Name: "license", Type: "choice"}, wantErr: true},
+		{name: "Invalid regex", variable: &Variable{Name: "module", Regex: "("}, wantErr: true},
+		{
+			name:     "Invalid bool default",
+			variable: &Variable{Name: "use_docker", Type: "bool", Default: "maybe"},
+			wantErr:  true,
+		},
+		{name: "Invalid int default", variable: &Variable{Name: "port", Type: "int", Default: "http"}, wantErr: true},
+		{
+			name:     "Default not in choices",
+			variable: &Variable{Name: "license", Type: "choice", Choices: StringList{"MIT", "GPL"}, Default: "BSD"},
+			wantErr:  true,
+		},
+		{
+			name:     "Default not matching regex",
+			variable: &Variable{Name: "module", Regex: "^[a-z.]+/", Default: "app"},
+			wantErr:  true,
+		},
+	}
+
+	for _, test := range tests {
+		err := test.variable.Validate()
+		if test.wantErr {
+			assert.Error(t, err, test.name)
+			continue
+		}
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.wantType, test.variable.Type, test.name)
+	}
+}
+
+func TestVariableParse(t *testing.T) {
+	license := &Variable{Name: "license", Type: VariableTypeChoice, Choices: StringList{"MIT", "GPL"}, Default: "MIT"}
+	module := &Variable{Name: "module", Type: VariableTypeString, Regex: `^[a-z.]+/\w+$`}
+
+	tests := []struct {
+		name     string
+		variable *Variable
+		input    string
+		want     interface{}
+		wantErr  bool
+	}{
+		{name: "String", variable: &Variable{Name: "author", Type: VariableTypeString}, input: " nikoksr ", want: "nikoksr"},
+		{name: "String default", variable: &Variable{Name: "author", Default: "nikoksr"}, want: "nikoksr"},
+		{name: "Bool", variable: &Variable{Name: "use_docker", Type: VariableTypeBool}, input: "true", want: true},
+		{
+			name:     "Bool default",
+			variable: &Variable{Name: "use_docker", Type: VariableTypeBool, Default: "false"},
+			want:     false,
+		},
+		{name: "Bad bool", variable: &Variable{Name: "use_docker", Type: VariableTypeBool}, input: "yes!", wantErr: true},
+		{name: "Int", variable: &Variable{Name: "port", Type: VariableTypeInt}, input: "8080", want: 8080},
+		{name: "Bad int", variable: &Variable{Name: "port", Type: VariableTypeInt}, input: "80.5", wantErr: true},
+		{name: "Choice", variable: license, input: "GPL", want: "GPL"},
+		{name: "Choice default", variable: license, want: "MIT"},
+		{name: "Choice not in list", variable: license, input: "BSD", wantErr: true},
+		{name: "Choices are case-sensitive", variable: license, input: "mit", wantErr: true},
+		{name: "Regex", variable: module, input: "example.com/app", want: "example.com/app"},
+		{name: "Regex mismatch", variable: module, input: "app", wantErr: true},
+		{name: "Missing required string", variable: &Variable{Name: "author", Type: VariableTypeString}, wantErr: true},
+		{name: "Missing required int", variable: &Variable{Name: "port", Type: VariableTypeInt}, input: " ", wantErr: true},
+		{name: "Missing required choice", variable: &Variable{Name: "license", Type: VariableTypeChoice}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := test.variable.Parse(test.input)
+		if test.wantErr {
+			assert.Error(t, err, test.name)
+			continue
+		}
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.want, got, test.name)
+	}
+}
+
+func TestVariableIsRequired(t *testing.T) {
+	assert.True(t, (&Variable{Name: "author"}).IsRequired())
+	assert.True(t, (&Variable{Name: "use_docker", Type: VariableTypeBool}).IsRequired())
+	assert.False(t, (&Variable{Name: "author", Default: "nikoksr"}).IsRequired())
+	assert.False(t, (&Variable{Name: "use_docker", Type: VariableTypeBool, Default: "false"}).IsRequired())
+}
@@ -12,22 +12,77 @@ type RemoveService interface {
 	PurgeProject(path string) error   // PurgeProject removes a soft-deleted project finally from storage.
 }
 
-// RemovePackage performs a soft-delete of a given package from storage.
+// RemovePackage performs a soft-delete of the current version of a given package from storage. Its previous versions
+// are removed finally, including their templates, plugins and variables.
 func (db *Database) RemovePackage(label string) error {
-	err := db.Connection.Delete(&models.Package{}, "label = ? AND deleted_at IS NULL", label).Error
-	if err == gorm.ErrRecordNotFound {
-		return &PackageNotFoundError{Label: label}
+	var archived []uint
+	err := db.Connection.Model(&models.Package{}).
+		Where("label = ? AND archived = ?", label, true).
+		Pluck("id", &archived).Error
+	if err != nil {
+		return err
 	}
-	return err
+	return db.Connection.Transaction(func(tx *gorm.DB) error {
+		err := deletePackages(tx, archived)
+		if err != nil {
+			return err
+		}
+		err = tx.Delete(&models.Package{}, "label = ? AND deleted_at IS NULL", label).Error
+		if err == gorm.ErrRecordNotFound {
+			return &PackageNotFoundError{Label: label}
+		}
+		return err
+	})
 }
 
-// PurgePackage removes a soft-deleted package finally from storage.
+// PurgePackage removes a soft-deleted package and all of its versions finally from storage, including their templates,
+// plugins and variables.
 func (db *Database) PurgePackage(label string) error {
-	err := db.Connection.Unscoped().Delete(&models.Package{}, "label = ?", label).Error
-	if err == gorm.ErrRecordNotFound {
+	var ids []uint
+	err := db.Connection.Unscoped().Model(&models.Package{}).Where("label = ?", label).Pluck("id", &ids).Error
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
 		return &PackageNotFoundError{Label: label}
 	}
-	return err
+	return db.Connection.Transaction(func(tx *gorm.DB) error {
+		return deletePackages(tx, ids)
+	})
+}
+
+// packageAssociations maps the join tables of packages to the column that references the associated record and its
+// model.
+var packageAssociations = []struct {
+	table  string
+	column string
+	model  interface{}
+}{
+	{table: "package_templates", column: "template_id", model: &models.Template{}},
+	{table: "package_plugins", column: "plugin_id", model: &models.Plugin{}},
+	{table: "package_variables", column: "variable_id", model: &models.Variable{}},
+}
+
+// deletePackages deletes the packages with the given ids finally from storage. Their templates, plugins and variables
+// are deleted too unless another package references them. The rows of the join tables are deleted explicitly because
+// not all databases enforce foreign key constraints.
+func deletePackages(tx *gorm.DB, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	for _, association := range packageAssociations {
+		owned := tx.Table(association.table).Select(association.column).Where("package_id IN ?", ids)
+		shared := tx.Table(association.table).Select(association.column).Where("package_id NOT IN ?", ids)
+		err := tx.Unscoped().Where("id IN (?) AND id NOT IN (?)", owned, shared).Delete(association.model).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Exec("DELETE FROM "+association.table+" WHERE package_id IN ?", ids).Error
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Unscoped().Delete(&models.Package{}, "id IN ?", ids).Error
 }
 
 // RemoveProject removes a project from storage.
@@ -39,11 +94,35 @@ func (db *Database) RemoveProject(path string) error {
 	return err
 }
 
-// PurgeProject removes a soft-deleted project finally from storage.
+// PurgeProject removes a soft-deleted project and its manifest finally from storage.
 func (db *Database) PurgeProject(path string) error {
-	err := db.Connection.Unscoped().Delete(&models.Project{}, "path = ?", path).Error
-	if err == gorm.ErrRecordNotFound {
-		return &ProjectNotFoundError{Path: path}
+	var projects []*models.Project
+	err := db.Connection.Unscoped().Find(&projects, "path = ?", path).Error
+	if err != nil {
+		return err
 	}
-	return err
+	return db.Connection.Transaction(func(tx *gorm.DB) error {
+		for _, project := range projects {
+			err := deleteManifests(tx, project.ID)
+			if err != nil {
+				return err
+			}
+		}
+		err := tx.Unscoped().Delete(&models.Project{}, "path = ?", path).Error
+		if err == gorm.ErrRecordNotFound {
+			return &ProjectNotFoundError{Path: path}
+		}
+		return err
+	})
+}
+
+// deleteManifests deletes the manifests of the project with the given id including their files. The files are deleted
+// explicitly because not all databases enforce foreign key constraints.
+func deleteManifests(tx *gorm.DB, projectID uint) error {
+	manifests := tx.Model(&models.Manifest{}).Select("id").Where("project_id = ?", projectID)
+	err := tx.Delete(&models.ManifestFile{}, "manifest_id IN (?)", manifests).Error
+	if err != nil {
+		return err
+	}
+	return tx.Delete(&models.Manifest{}, "project_id = ?", projectID).Error
 }
@@ -1,35 +1,123 @@
 package storage
 
 import (
+	"fmt"
+
 	"github.com/nikoksr/proji/storage/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type SaveService interface {
 	SavePackage(pkg *models.Package) error     // SavePackage saves a package to storage.
+	ReplacePackage(pkg *models.Package) error  // ReplacePackage saves a package to storage as the current version.
 	SaveProject(project *models.Project) error // SaveProject saves a project to storage.
 }
 
-// SavePackage saves a package to storage.
+// SavePackage saves a package to storage. Packages with an ambiguous plugin execution order are rejected, as well as
+// packages that extend packages which don't exist or can't be resolved together with them.
+//
+// If a package with the same label exists already, the package is saved as its new current version and the previous
+// versions are kept as its history; see LoadPackageHistory. A PackageExistsError is returned if the version was saved
+// before. Projects follow the current version of their package, so a PackageReplaceError is returned if the version is
+// not newer than the current one or if it runs its plugins in a sandbox and the current one doesn't, or vice versa;
+// e.g. if a package from a remote repository would replace a local package. See ReplacePackage.
 func (db *Database) SavePackage(pkg *models.Package) error {
-	err := db.Connection.First(pkg, "label = ?", pkg.Label).Error
-	if err == nil {
-		return &PackageExistsError{Label: pkg.Label}
+	return db.savePackage(pkg, false)
+}
+
+// ReplacePackage saves a package to storage like SavePackage, but makes it the current version of its label even if it
+// is not newer than the current version or runs its plugins differently.
+func (db *Database) ReplacePackage(pkg *models.Package) error {
+	return db.savePackage(pkg, true)
+}
+
+// savePackage saves a package to storage as the current version of its label. Unless force is set, the package has to
+// be newer than the current version and run its plugins the same way; see SavePackage.
+func (db *Database) savePackage(pkg *models.Package, force bool) error {
+	err := pkg.ValidatePluginOrder()
+	if err != nil {
+		return err
+	}
+	resolved := *pkg
+	err = db.resolveExtends(&resolved)
+	if err != nil {
+		return err
+	}
+	var versions int64
+	err = db.Connection.Model(&models.Package{}).
+		Where("label = ? AND version = ?", pkg.Label, pkg.Version).
+		Count(&versions).Error
+	if err != nil {
+		return err
+	}
+	if versions > 0 {
+		return &PackageExistsError{Label: pkg.Label, Version: pkg.Version}
 	}
+	if !force {
+		err = db.checkReplacement(pkg)
+		if err != nil {
+			return err
+		}
+	}
+	return db.Connection.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.Package{}).Where("label = ?", pkg.Label).Update("archived", true).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(pkg).Error
+	})
+}
+
+// checkReplacement returns a PackageReplaceError if the package may not replace the current version of its label
+// without being forced to. Nothing is replaced if no package with the label exists.
+func (db *Database) checkReplacement(pkg *models.Package) error {
+	var current models.Package
+	err := db.Connection.First(&current, "label = ? AND archived = ?", pkg.Label, false).Error
 	if err == gorm.ErrRecordNotFound {
-		return db.Connection.Create(pkg).Error
+		return nil
 	}
-	return err
+	if err != nil {
+		return err
+	}
+
+	if models.CompareVersions(pkg.Version, current.Version) <= 0 {
+		return &PackageReplaceError{
+			Label: pkg.Label,
+			Reason: fmt.Sprintf(
+				"version '%s' is not newer than the current version '%s'", pkg.Version, current.Version,
+			),
+		}
+	}
+	if pkg.Sandboxed != current.Sandboxed {
+		reason := "its plugins would run in a sandbox but the plugins of the current version don't"
+		if current.Sandboxed {
+			reason = "its plugins would run without the sandbox that the plugins of the current version run in"
+		}
+		return &PackageReplaceError{Label: pkg.Label, Reason: reason}
+	}
+	return nil
 }
 
-// SaveProject saves a project to storage.
+// SaveProject saves a project and its manifest to storage. The package of the project is only referenced, it has to be
+// saved already.
 func (db *Database) SaveProject(project *models.Project) error {
 	err := db.Connection.First(project, "path = ?", project.Path).Error
 	if err == nil {
 		return &ProjectExistsError{Path: project.Path}
 	}
-	if err == gorm.ErrRecordNotFound {
-		return db.Connection.Create(project).Error
+	if err != gorm.ErrRecordNotFound {
+		return err
+	}
+	if project.Package != nil {
+		project.PackageID = project.Package.ID
 	}
-	return err
+	return db.Connection.Transaction(func(tx *gorm.DB) error {
+		err := tx.Omit(clause.Associations).Create(project).Error
+		if err != nil || project.Manifest == nil {
+			return err
+		}
+		project.Manifest.ProjectID = project.ID
+		return tx.Create(project.Manifest).Error
+	})
 }
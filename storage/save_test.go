@@ -0,0 +1,258 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nikoksr/proji/storage/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestDatabase returns a migrated sqlite database inside of a new temporary folder.
+func newTestDatabase(t *testing.T) *Database {
+	tmpDir, err := ioutil.TempDir("", "proji-storage")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+
+	service, err := newDatabaseService(sqliteDriver, filepath.Join(tmpDir, "proji.sqlite3"))
+	assert.NoError(t, err)
+	assert.NoError(t, service.Migrate())
+	return service.(*Database)
+}
+
+// testPackage returns a package with a single template whose content identifies the version.
+func testPackage(label, version string) *models.Package {
+	return &models.Package{
+		Name:      label,
+		Label:     label,
+		Version:   version,
+		Templates: []*models.Template{{Destination: "VERSION", IsFile: true, Content: version}},
+	}
+}
+
+func TestSavePackage(t *testing.T) {
+	type save struct {
+		label     string
+		version   string
+		sandboxed bool
+		force     bool
+		wantErr   error
+	}
+	tests := []struct {
+		name        string
+		saves       []save
+		wantCurrent string   // Version of the current package with the label go
+		wantHistory []string // Versions of the package with the label go in the order they were saved
+	}{
+		{
+			name:        "Single version",
+			saves:       []save{{label: "go", version: "1.0.0"}},
+			wantCurrent: "1.0.0",
+			wantHistory: []string{"1.0.0"},
+		},
+		{
+			name:        "New version",
+			saves:       []save{{label: "go", version: "1.0.0"}, {label: "go", version: "1.1.0"}},
+			wantCurrent: "1.1.0",
+			wantHistory: []string{"1.0.0", "1.1.0"},
+		},
+		{
+			name: "Older version",
+			saves: []save{
+				{label: "go", version: "1.1.0"},
+				{label: "go", version: "1.0.1", wantErr: &PackageReplaceError{}},
+			},
+			wantCurrent: "1.1.0",
+			wantHistory: []string{"1.1.0"},
+		},
+		{
+			name: "Older version forced",
+			saves: []save{
+				{label: "go", version: "1.1.0"},
+				{label: "go", version: "1.0.1", force: true},
+			},
+			wantCurrent: "1.0.1",
+			wantHistory: []string{"1.1.0", "1.0.1"},
+		},
+		{
+			name: "Unversioned after versioned",
+			saves: []save{
+				{label: "go", version: "1.0.0"},
+				{label: "go", wantErr: &PackageReplaceError{}},
+			},
+			wantCurrent: "1.0.0",
+			wantHistory: []string{"1.0.0"},
+		},
+		{
+			name: "Sandboxed replacing trusted",
+			saves: []save{
+				{label: "go", version: "1.0.0"},
+				{label: "go", version: "1.1.0", sandboxed: true, wantErr: &PackageReplaceError{}},
+			},
+			wantCurrent: "1.0.0",
+			wantHistory: []string{"1.0.0"},
+		},
+		{
+			name: "Trusted replacing sandboxed",
+			saves: []save{
+				{label: "go", version: "1.0.0", sandboxed: true},
+				{label: "go", version: "1.1.0", wantErr: &PackageReplaceError{}},
+			},
+			wantCurrent: "1.0.0",
+			wantHistory: []string{"1.0.0"},
+		},
+		{
+			name: "Sandboxed replacing trusted forced",
+			saves: []save{
+				{label: "go", version: "1.0.0"},
+				{label: "go", version: "1.1.0", sandboxed: true, force: true},
+			},
+			wantCurrent: "1.1.0",
+			wantHistory: []string{"1.0.0", "1.1.0"},
+		},
+		{
+			name:        "Unversioned",
+			saves:       []save{{label: "go"}, {label: "go", version: "1.0.0"}},
+			wantCurrent: "1.0.0",
+			wantHistory: []string{"", "1.0.0"},
+		},
+		{
+			name: "Existing version",
+			saves: []save{
+				{label: "go", version: "1.0.0"},
+				{label: "go", version: "1.1.0"},
+				{label: "go", version: "1.0.0", wantErr: &PackageExistsError{}},
+			},
+			wantCurrent: "1.1.0",
+			wantHistory: []string{"1.0.0", "1.1.0"},
+		},
+		{
+			name:        "Existing unversioned",
+			saves:       []save{{label: "go"}, {label: "go", wantErr: &PackageExistsError{}}},
+			wantCurrent: "",
+			wantHistory: []string{""},
+		},
+		{
+			name:        "Other label",
+			saves:       []save{{label: "go", version: "1.0.0"}, {label: "py", version: "1.1.0"}},
+			wantCurrent: "1.0.0",
+			wantHistory: []string{"1.0.0"},
+		},
+	}
+
+	for _, test := range tests {
+		db := newTestDatabase(t)
+		for _, save := range test.saves {
+			pkg := testPackage(save.label, save.version)
+			pkg.Sandboxed = save.sandboxed
+			var err error
+			if save.force {
+				err = db.ReplacePackage(pkg)
+			} else {
+				err = db.SavePackage(pkg)
+			}
+			if save.wantErr != nil {
+				assert.IsType(t, save.wantErr, err, test.name)
+				continue
+			}
+			assert.NoError(t, err, test.name)
+		}
+
+		current, err := db.LoadPackage("go")
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.wantCurrent, current.Version, test.name)
+		assert.Equal(t, test.wantCurrent, current.Templates[0].Content, test.name)
+
+		history, err := db.LoadPackageHistory("go")
+		assert.NoError(t, err, test.name)
+		versions := make([]string, 0, len(history))
+		for _, pkg := range history {
+			versions = append(versions, pkg.Version)
+			assert.Equal(t, pkg.Version != test.wantCurrent, pkg.Archived, test.name)
+		}
+		assert.Equal(t, test.wantHistory, versions, test.name)
+
+		packages, err := db.LoadPackages()
+		assert.NoError(t, err, test.name)
+		for _, pkg := range packages {
+			assert.False(t, pkg.Archived, test.name)
+		}
+	}
+}
+
+func TestSavePackageInvalid(t *testing.T) {
+	db := newTestDatabase(t)
+	ambiguous := testPackage("go", "1.0.0")
+	ambiguous.Plugins = []*models.Plugin{{Path: "a.lua", ExecNumber: 1}, {Path: "b.lua", ExecNumber: 1}}
+	assert.Error(t, db.SavePackage(ambiguous))
+
+	orphan := testPackage("go", "1.0.0")
+	orphan.Extends = models.StringList{"unknown"}
+	assert.Error(t, db.SavePackage(orphan))
+
+	_, err := db.LoadPackage("go")
+	assert.IsType(t, &PackageNotFoundError{}, err)
+}
+
+func TestRemovePackageVersions(t *testing.T) {
+	db := newTestDatabase(t)
+	assert.NoError(t, db.SavePackage(testPackage("go", "1.0.0")))
+	assert.NoError(t, db.SavePackage(testPackage("go", "1.1.0")))
+
+	assert.NoError(t, db.RemovePackage("go"))
+	_, err := db.LoadPackage("go")
+	assert.IsType(t, &PackageNotFoundError{}, err)
+	_, err = db.LoadPackageHistory("go")
+	assert.IsType(t, &PackageNotFoundError{}, err)
+
+	// The label can be used again
+	assert.NoError(t, db.SavePackage(testPackage("go", "1.0.0")))
+	history, err := db.LoadPackageHistory("go")
+	assert.NoError(t, err)
+	assert.Len(t, history, 1)
+}
+
+func TestRemovePackageAssociations(t *testing.T) {
+	db := newTestDatabase(t)
+	for _, version := range []string{"1.0.0", "1.1.0"} {
+		pkg := testPackage("go", version)
+		pkg.Plugins = []*models.Plugin{{Path: "init.lua", ExecNumber: 1}}
+		pkg.Variables = []*models.Variable{{Name: "license", Default: "MIT"}}
+		assert.NoError(t, db.SavePackage(pkg))
+	}
+	other := testPackage("py", "1.0.0")
+	other.Plugins = []*models.Plugin{{Path: "init.lua", ExecNumber: 1}}
+	assert.NoError(t, db.SavePackage(other))
+
+	// count returns the number of rows of the table including soft-deleted ones.
+	count := func(table string) int64 {
+		var rows int64
+		assert.NoError(t, db.Connection.Table(table).Count(&rows).Error, table)
+		return rows
+	}
+
+	// Only the archived version is removed finally; the current one is soft-deleted and keeps its associations.
+	assert.NoError(t, db.RemovePackage("go"))
+	for _, table := range []string{"package_templates", "package_plugins", "templates", "plugins"} {
+		assert.Equal(t, int64(2), count(table), table)
+	}
+	for _, table := range []string{"package_variables", "variables"} {
+		assert.Equal(t, int64(1), count(table), table)
+	}
+
+	assert.NoError(t, db.PurgePackage("go"))
+	for _, table := range []string{"package_templates", "package_plugins", "templates", "plugins"} {
+		assert.Equal(t, int64(1), count(table), table)
+	}
+	for _, table := range []string{"package_variables", "variables"} {
+		assert.Equal(t, int64(0), count(table), table)
+	}
+	assert.IsType(t, &PackageNotFoundError{}, db.PurgePackage("go"))
+
+	pkg, err := db.LoadPackage("py")
+	assert.NoError(t, err)
+	assert.Len(t, pkg.Templates, 1)
+	assert.Len(t, pkg.Plugins, 1)
+}
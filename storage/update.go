@@ -7,6 +7,8 @@ import (
 
 type UpdateService interface {
 	UpdateProjectLocation(oldPath, newPath string) error // UpdateProjectLocation updates the path of a project in storage.
+	UpdateProjectManifest(project *models.Project) error // UpdateProjectManifest replaces the manifest of a project in storage.
+	RollbackPackage(label, version string) error         // RollbackPackage makes a previous version of a package its current version.
 }
 
 // UpdateProjectLocation updates the location of a project in storage.
@@ -17,3 +19,50 @@ func (db *Database) UpdateProjectLocation(oldPath, newPath string) error {
 	}
 	return err
 }
+
+// UpdateProjectManifest replaces the stored manifest of a project with its current one. The project is linked to the
+// version of its package that the manifest was generated from.
+func (db *Database) UpdateProjectManifest(project *models.Project) error {
+	if project.Manifest == nil {
+		return nil
+	}
+	return db.Connection.Transaction(func(tx *gorm.DB) error {
+		err := deleteManifests(tx, project.ID)
+		if err != nil {
+			return err
+		}
+		if project.Package != nil && project.Package.ID != project.PackageID {
+			project.PackageID = project.Package.ID
+			err = tx.Model(&models.Project{}).Where("id = ?", project.ID).Update("package_id", project.PackageID).Error
+			if err != nil {
+				return err
+			}
+		}
+		project.Manifest.ID = 0
+		project.Manifest.ProjectID = project.ID
+		for _, file := range project.Manifest.Files {
+			file.ID = 0
+		}
+		return tx.Create(project.Manifest).Error
+	})
+}
+
+// RollbackPackage makes the given version of a package its current version again. Newer versions are kept; they can be
+// made current again the same way.
+func (db *Database) RollbackPackage(label, version string) error {
+	var pkg models.Package
+	err := db.Connection.First(&pkg, "label = ? AND version = ?", label, version).Error
+	if err == gorm.ErrRecordNotFound {
+		return &PackageNotFoundError{Label: label, Version: version}
+	}
+	if err != nil {
+		return err
+	}
+	return db.Connection.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.Package{}).Where("label = ?", label).Update("archived", true).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.Package{}).Where("id = ?", pkg.ID).Update("archived", false).Error
+	})
+}
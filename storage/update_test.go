@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollbackPackage(t *testing.T) {
+	tests := []struct {
+		name        string
+		rollbacks   []string
+		wantCurrent string
+		wantErr     bool
+	}{
+		{name: "No rollback", wantCurrent: "1.2.0"},
+		{name: "Previous version", rollbacks: []string{"1.1.0"}, wantCurrent: "1.1.0"},
+		{name: "First version", rollbacks: []string{"1.0.0"}, wantCurrent: "1.0.0"},
+		{name: "Current version", rollbacks: []string{"1.2.0"}, wantCurrent: "1.2.0"},
+		{name: "Back and forth", rollbacks: []string{"1.0.0", "1.2.0", "1.1.0"}, wantCurrent: "1.1.0"},
+		{name: "Unknown version", rollbacks: []string{"2.0.0"}, wantCurrent: "1.2.0", wantErr: true},
+	}
+
+	for _, test := range tests {
+		db := newTestDatabase(t)
+		for _, version := range []string{"1.0.0", "1.1.0", "1.2.0"} {
+			assert.NoError(t, db.SavePackage(testPackage("go", version)), test.name)
+		}
+
+		for _, version := range test.rollbacks {
+			err := db.RollbackPackage("go", version)
+			if test.wantErr {
+				assert.IsType(t, &PackageNotFoundError{}, err, test.name)
+				continue
+			}
+			assert.NoError(t, err, test.name)
+		}
+
+		current, err := db.LoadPackage("go")
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.wantCurrent, current.Version, test.name)
+		assert.Equal(t, test.wantCurrent, current.Templates[0].Content, test.name)
+
+		// Rollbacks never remove versions
+		history, err := db.LoadPackageHistory("go")
+		assert.NoError(t, err, test.name)
+		assert.Len(t, history, 3, test.name)
+		current = nil
+		for _, pkg := range history {
+			if !pkg.Archived {
+				assert.Nil(t, current, test.name)
+				current = pkg
+			}
+		}
+		assert.NotNil(t, current, test.name)
+
+		// Saving a new version after a rollback makes it the current one
+		assert.NoError(t, db.SavePackage(testPackage("go", "1.3.0")), test.name)
+		current, err = db.LoadPackage("go")
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, "1.3.0", current.Version, test.name)
+	}
+}
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"bufio"
 	"fmt"
 	"io"
 	"os"
@@ -12,6 +13,9 @@ import (
 	"github.com/jedib0t/go-pretty/v6/table"
 )
 
+//nolint:gochecknoglobals
+var stdinReader = bufio.NewReader(os.Stdin)
+
 // DoesPathExist checks if a given path exists in the filesystem.
 func DoesPathExist(path string) bool {
 	_, err := os.Stat(path)
@@ -52,6 +56,16 @@ func WantTo(question string) bool {
 	}
 }
 
+// Prompt prints the given question and returns the line the user entered, without the trailing newline.
+func Prompt(question string) (string, error) {
+	fmt.Print(question)
+	input, err := stdinReader.ReadString('\n')
+	if err != nil && !(err == io.EOF && len(input) > 0) {
+		return "", err
+	}
+	return strings.TrimRight(input, "\r\n"), nil
+}
+
 // IsInSlice returns true if a given string is found in the given slice and false if not.
 func IsInSlice(slice []string, val string) bool {
 	for _, item := range slice {